@@ -2,17 +2,22 @@ package accountapi
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // Account is object that holds account details. Optional attributes can be omitted.
 type Account struct {
-	Type           string            `json:"type"`
-	ID             string            `json:"id"`
-	OrganisationID string            `json:"organisation_id"`
-	Version        int               `json:"version,omitempty"`
-	CreatedOn      string            `json:"created_on,omitempty"`
-	ModifiedOn     string            `json:"modified_on,omitempty"`
-	Attributes     countryAttributes `json:"attributes"`
+	Type           string `json:"type"`
+	ID             string `json:"id"`
+	OrganisationID string `json:"organisation_id"`
+	Version        int    `json:"version,omitempty"`
+	// CreatedOn and ModifiedOn were strings before the Timestamp type, which breaks callers
+	// that used them as such; String returns the text as received from the API.
+	CreatedOn  Timestamp         `json:"created_on,omitzero"`
+	ModifiedOn Timestamp         `json:"modified_on,omitzero"`
+	Attributes countryAttributes `json:"attributes"`
+	// Relationships to other resources, e.g. the master account
+	Relationships Relationships `json:"relationships,omitempty"`
 }
 
 type countryAttributes struct {
@@ -33,6 +38,24 @@ type countryAttributes struct {
 	Status                  string   `json:"status,omitempty"`
 }
 
+// Age returns how long ago the account was created, or zero when the creation time is unknown
+func (account Account) Age() time.Duration {
+	if account.CreatedOn.IsZero() {
+		return 0
+	}
+	return time.Since(account.CreatedOn.Time)
+}
+
+// IsNewerThan reports whether the account is a later state than other. The API increments
+// Version on every change, so a higher version is newer; of equal versions, the one
+// modified later is newer.
+func (account Account) IsNewerThan(other Account) bool {
+	if account.Version != other.Version {
+		return account.Version > other.Version
+	}
+	return account.ModifiedOn.After(other.ModifiedOn.Time)
+}
+
 // Marshal input object (of Account type) to JSON
 // TODO: Apply inheritance to accept any inherited type
 func (input Account) toJSON() (string, error) {
@@ -0,0 +1,123 @@
+package accountapitest
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Memory is an accountapi.AccountService that keeps accounts in memory, without HTTP.
+// It behaves like the client talking to Server: it validates, versions and timestamps accounts
+// the same way and fails with the same *accountapi.APIError. Seed, Accounts, FailNext,
+// SetLatency and SetClock of the embedded Handler work as for Server.
+type Memory struct {
+	*Handler
+}
+
+var _ accountapi.AccountService = (*Memory)(nil)
+
+// NewMemory returns an empty in-memory account service
+func NewMemory() *Memory {
+	return &Memory{NewHandler(DefaultURI)}
+}
+
+// CreateAccountContext stores given account as version 0
+func (memory *Memory) CreateAccountContext(ctx context.Context, account accountapi.Account) (accountapi.Account, error) {
+	if err := memory.begin(ctx); err != nil {
+		return accountapi.Account{}, err
+	}
+	created, apiErr := memory.createAccount(account)
+	if apiErr != nil {
+		return accountapi.Account{}, apiErr
+	}
+	return created, nil
+}
+
+// FetchAccountContext returns the stored account with given ID
+func (memory *Memory) FetchAccountContext(ctx context.Context, accountID string) (accountapi.Account, error) {
+	if err := memory.begin(ctx); err != nil {
+		return accountapi.Account{}, err
+	}
+	account, apiErr := memory.fetchAccount(accountID)
+	if apiErr != nil {
+		return accountapi.Account{}, apiErr
+	}
+	return account, nil
+}
+
+// ListAccountsContext returns a page of stored accounts matching given filter, in creation order
+func (memory *Memory) ListAccountsContext(ctx context.Context, pageNumber int, pageSize int, filter accountapi.Filter) ([]accountapi.Account, error) {
+	if err := memory.begin(ctx); err != nil {
+		return nil, err
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	query := url.Values{}
+	for key, value := range filter {
+		query.Set("filter["+key+"]", value)
+	}
+	matching := memory.matchingAccounts(query)
+
+	accounts := []accountapi.Account{}
+	for i := pageNumber * pageSize; i >= 0 && i < len(matching) && i < (pageNumber+1)*pageSize; i++ {
+		accounts = append(accounts, matching[i])
+	}
+	return accounts, nil
+}
+
+// UpdateAccountContext changes non-empty attributes of given account, like the client does
+func (memory *Memory) UpdateAccountContext(ctx context.Context, account accountapi.Account) (accountapi.Account, error) {
+	if err := memory.begin(ctx); err != nil {
+		return accountapi.Account{}, err
+	}
+	var changes map[string]interface{}
+	bAttributes, _ := json.Marshal(account.Attributes)
+	json.Unmarshal(bAttributes, &changes)
+	for key, value := range changes {
+		if value == "" {
+			delete(changes, key)
+		}
+	}
+
+	updated, apiErr := memory.patchAccount(account.ID, account.Version, changes)
+	if apiErr != nil {
+		return accountapi.Account{}, apiErr
+	}
+	return updated, nil
+}
+
+// DeleteAccountContext removes given version of the account
+func (memory *Memory) DeleteAccountContext(ctx context.Context, accountID string, version int) error {
+	if err := memory.begin(ctx); err != nil {
+		return err
+	}
+	if apiErr := memory.deleteAccount(accountID, version); apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
+
+// begin waits the configured latency and returns the next configured failure, if any
+func (memory *Memory) begin(ctx context.Context) error {
+	failure, latency := memory.nextFailure()
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failure != nil {
+		return &accountapi.APIError{StatusCode: failure.StatusCode, ErrorMessage: failure.ErrorMessage}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package accountapitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Methods of accountapi.AccountService, as expected and recorded by Mock
+const (
+	CreateAccount = "CreateAccountContext"
+	FetchAccount  = "FetchAccountContext"
+	ListAccounts  = "ListAccountsContext"
+	UpdateAccount = "UpdateAccountContext"
+	DeleteAccount = "DeleteAccountContext"
+)
+
+// ErrUnexpectedCall is returned by Mock for calls that match no expectation
+var ErrUnexpectedCall = errors.New("Unexpected call of account service mock")
+
+// Any matches any argument of an expectation
+var Any = anyArgument{}
+
+type anyArgument struct{}
+
+// Call is a call recorded by Mock. Args are the arguments after the context.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+func (call Call) String() string {
+	return fmt.Sprintf("%v%v", call.Method, call.Args)
+}
+
+// Expectation is a call Mock expects, with the results it returns
+type Expectation struct {
+	method   string
+	args     []interface{}
+	account  accountapi.Account
+	accounts []accountapi.Account
+	err      error
+	times    int
+	calls    int
+}
+
+// ReturnAccount sets the results of expected create, fetch and update calls
+func (expectation *Expectation) ReturnAccount(account accountapi.Account, err error) *Expectation {
+	expectation.account, expectation.err = account, err
+	return expectation
+}
+
+// ReturnAccounts sets the results of expected list calls
+func (expectation *Expectation) ReturnAccounts(accounts []accountapi.Account, err error) *Expectation {
+	expectation.accounts, expectation.err = accounts, err
+	return expectation
+}
+
+// ReturnError sets the error of expected calls, e.g. of delete calls
+func (expectation *Expectation) ReturnError(err error) *Expectation {
+	expectation.err = err
+	return expectation
+}
+
+// Times limits how often the expectation matches. By default, it matches any number of calls,
+// but at least one.
+func (expectation *Expectation) Times(times int) *Expectation {
+	expectation.times = times
+	return expectation
+}
+
+func (expectation *Expectation) String() string {
+	return Call{expectation.method, expectation.args}.String()
+}
+
+// Mock is an accountapi.AccountService that returns results of expectations and records calls,
+// for table-driven tests of code depending on the service:
+//
+//	mock := accountapitest.NewMock(t)
+//	mock.On(accountapitest.FetchAccount, id).ReturnAccount(account, nil).Times(1)
+//	...
+//	mock.AssertExpectations()
+type Mock struct {
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+var _ accountapi.AccountService = (*Mock)(nil)
+
+// NewMock returns a mock without expectations. Unexpected calls are reported as errors of t.
+func NewMock(t testing.TB) *Mock {
+	return &Mock{t: t}
+}
+
+// On expects a call of given method with given arguments, excluding the context. Arguments
+// are compared deeply, Any matches any argument. Of several matching expectations, the first
+// one that is not used up returns its results.
+func (mock *Mock) On(method string, args ...interface{}) *Expectation {
+	switch method {
+	case CreateAccount, FetchAccount, ListAccounts, UpdateAccount, DeleteAccount:
+	default:
+		panic(fmt.Sprintf("accountapitest: %v is not a method of AccountService", method))
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	expectation := &Expectation{method: method, args: args}
+	mock.expectations = append(mock.expectations, expectation)
+	return expectation
+}
+
+// Calls returns all recorded calls in order
+func (mock *Mock) Calls() []Call {
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	return append([]Call(nil), mock.calls...)
+}
+
+// CallsOf returns recorded calls of given method in order
+func (mock *Mock) CallsOf(method string) []Call {
+	var calls []Call
+	for _, call := range mock.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// AssertExpectations reports expectations that were not called, or not as often as set by Times
+func (mock *Mock) AssertExpectations() {
+	mock.t.Helper()
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	for _, expectation := range mock.expectations {
+		switch {
+		case expectation.times == 0 && expectation.calls == 0:
+			mock.t.Errorf("Expected call %v was not made", expectation)
+		case expectation.times > 0 && expectation.calls != expectation.times:
+			mock.t.Errorf("Expected call %v %v times, got %v", expectation, expectation.times, expectation.calls)
+		}
+	}
+}
+
+// CreateAccountContext returns results of the matching CreateAccount expectation
+func (mock *Mock) CreateAccountContext(ctx context.Context, account accountapi.Account) (accountapi.Account, error) {
+	expectation := mock.called(CreateAccount, account)
+	if expectation == nil {
+		return accountapi.Account{}, ErrUnexpectedCall
+	}
+	return expectation.account, expectation.err
+}
+
+// FetchAccountContext returns results of the matching FetchAccount expectation
+func (mock *Mock) FetchAccountContext(ctx context.Context, accountID string) (accountapi.Account, error) {
+	expectation := mock.called(FetchAccount, accountID)
+	if expectation == nil {
+		return accountapi.Account{}, ErrUnexpectedCall
+	}
+	return expectation.account, expectation.err
+}
+
+// ListAccountsContext returns results of the matching ListAccounts expectation
+func (mock *Mock) ListAccountsContext(ctx context.Context, pageNumber int, pageSize int, filter accountapi.Filter) ([]accountapi.Account, error) {
+	expectation := mock.called(ListAccounts, pageNumber, pageSize, filter)
+	if expectation == nil {
+		return nil, ErrUnexpectedCall
+	}
+	return expectation.accounts, expectation.err
+}
+
+// UpdateAccountContext returns results of the matching UpdateAccount expectation
+func (mock *Mock) UpdateAccountContext(ctx context.Context, account accountapi.Account) (accountapi.Account, error) {
+	expectation := mock.called(UpdateAccount, account)
+	if expectation == nil {
+		return accountapi.Account{}, ErrUnexpectedCall
+	}
+	return expectation.account, expectation.err
+}
+
+// DeleteAccountContext returns the error of the matching DeleteAccount expectation
+func (mock *Mock) DeleteAccountContext(ctx context.Context, accountID string, version int) error {
+	expectation := mock.called(DeleteAccount, accountID, version)
+	if expectation == nil {
+		return ErrUnexpectedCall
+	}
+	return expectation.err
+}
+
+// called records the call and returns the matching expectation, or nil when the call was unexpected
+func (mock *Mock) called(method string, args ...interface{}) *Expectation {
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	call := Call{method, args}
+	mock.calls = append(mock.calls, call)
+
+	for _, expectation := range mock.expectations {
+		if expectation.method != method || len(expectation.args) != len(args) {
+			continue
+		}
+		if expectation.times > 0 && expectation.calls >= expectation.times {
+			continue
+		}
+		matches := true
+		for i, arg := range args {
+			matches = matches && matchesArgument(expectation.args[i], arg)
+		}
+		if matches {
+			expectation.calls++
+			return expectation
+		}
+	}
+	mock.t.Errorf("Unexpected call %v", call)
+	return nil
+}
+
+// matchesArgument compares arguments deeply. Untyped nil matches nil maps and slices, e.g. a nil filter.
+func matchesArgument(expected interface{}, actual interface{}) bool {
+	if expected == Any {
+		return true
+	}
+	if expected == nil {
+		value := reflect.ValueOf(actual)
+		switch {
+		case actual == nil:
+			return true
+		case value.Kind() == reflect.Map || value.Kind() == reflect.Slice || value.Kind() == reflect.Ptr:
+			return value.IsNil()
+		}
+		return false
+	}
+	return reflect.DeepEqual(expected, actual)
+}
@@ -0,0 +1,345 @@
+// Package accountapitest provides a stateful, in-process fake of the bank's Account API
+// for use in tests of code built on top of the accountapi package.
+package accountapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// DefaultURI is the path under which accounts are served, as in the bank's API
+const DefaultURI = "/v1/organisation/accounts/"
+
+// defaultPageSize is used when list request does not specify page[size]
+const defaultPageSize = 100
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Failure describes an error response that the fake returns instead of handling a request
+type Failure struct {
+	StatusCode   int
+	ErrorMessage string
+}
+
+// Handler is an http.Handler that keeps accounts in memory and implements
+// create, fetch, list, delete and patch semantics of the Account API
+type Handler struct {
+	mu       sync.Mutex
+	uri      string
+	accounts map[string]accountapi.Account
+	order    []string
+	latency  time.Duration
+	failures []Failure
+	now      func() time.Time
+}
+
+// NewHandler returns an empty fake serving accounts under given URI
+func NewHandler(uri string) *Handler {
+	if !strings.HasSuffix(uri, "/") {
+		uri = uri + "/"
+	}
+	return &Handler{
+		uri:      uri,
+		accounts: map[string]accountapi.Account{},
+		now:      time.Now,
+	}
+}
+
+// Server is a fake Account API listening on a local address
+type Server struct {
+	*Handler
+	*httptest.Server
+}
+
+// NewServer starts a fake Account API serving accounts under DefaultURI.
+// Callers should Close it when done.
+func NewServer() *Server {
+	handler := NewHandler(DefaultURI)
+	return &Server{handler, httptest.NewServer(handler)}
+}
+
+// Config returns a configuration that points the accountapi client at the fake server
+func (server *Server) Config() accountapi.Configuration {
+	return accountapi.Configuration{AccountAPIUrl: server.URL + server.uri}
+}
+
+// Seed stores given accounts as if they were created
+func (handler *Handler) Seed(accounts ...accountapi.Account) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	for _, account := range accounts {
+		if _, exists := handler.accounts[account.ID]; !exists {
+			handler.order = append(handler.order, account.ID)
+		}
+		handler.accounts[account.ID] = account
+	}
+}
+
+// Accounts returns all stored accounts in creation order
+func (handler *Handler) Accounts() []accountapi.Account {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	accounts := make([]accountapi.Account, 0, len(handler.order))
+	for _, id := range handler.order {
+		accounts = append(accounts, handler.accounts[id])
+	}
+	return accounts
+}
+
+// SetLatency delays every following response by given duration
+func (handler *Handler) SetLatency(latency time.Duration) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	handler.latency = latency
+}
+
+// FailNext makes the next count requests fail with given status code and error message
+func (handler *Handler) FailNext(count int, statusCode int, errorMessage string) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	for i := 0; i < count; i++ {
+		handler.failures = append(handler.failures, Failure{statusCode, errorMessage})
+	}
+}
+
+// SetClock replaces the clock used for created_on and modified_on timestamps
+func (handler *Handler) SetClock(now func() time.Time) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	handler.now = now
+}
+
+// ServeHTTP dispatches requests under the accounts URI
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	failure, latency := handler.nextFailure()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != nil {
+		writeError(w, failure.StatusCode, failure.ErrorMessage)
+		return
+	}
+
+	path := r.URL.Path
+	if path+"/" == handler.uri {
+		path = handler.uri
+	}
+	if !strings.HasPrefix(path, handler.uri) {
+		writeError(w, http.StatusNotFound, "route not found")
+		return
+	}
+	id := strings.TrimPrefix(path, handler.uri)
+
+	switch {
+	case id == "" && r.Method == "POST":
+		handler.create(w, r)
+	case id == "" && r.Method == "GET":
+		handler.list(w, r)
+	case id != "" && r.Method == "GET":
+		handler.fetch(w, id)
+	case id != "" && r.Method == "DELETE":
+		handler.delete(w, r, id)
+	case id != "" && r.Method == "PATCH":
+		handler.patch(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (handler *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var body accountapi.Document[accountapi.Account]
+	bReqBody, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(bReqBody, &body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	account, apiErr := handler.createAccount(body.Data)
+	if apiErr != nil {
+		writeError(w, apiErr.StatusCode, apiErr.ErrorMessage)
+		return
+	}
+	handler.writeAccount(w, http.StatusCreated, account)
+}
+
+func (handler *Handler) fetch(w http.ResponseWriter, id string) {
+	account, apiErr := handler.fetchAccount(id)
+	if apiErr != nil {
+		writeError(w, apiErr.StatusCode, apiErr.ErrorMessage)
+		return
+	}
+	handler.writeAccount(w, http.StatusOK, account)
+}
+
+func (handler *Handler) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pageSize := defaultPageSize
+	if size := query.Get("page[size]"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "page[size] must be a positive integer")
+			return
+		}
+		pageSize = parsed
+	}
+
+	pageNumber := 0
+	switch number := query.Get("page[number]"); number {
+	case "", "first", "last":
+	default:
+		parsed, err := strconv.Atoi(number)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "page[number] must be a non-negative integer")
+			return
+		}
+		pageNumber = parsed
+	}
+
+	matching := handler.matchingAccounts(query)
+	lastPage := 0
+	if len(matching) > 0 {
+		lastPage = (len(matching) - 1) / pageSize
+	}
+	if query.Get("page[number]") == "last" {
+		pageNumber = lastPage
+	}
+
+	data := []accountapi.Account{}
+	for i := pageNumber * pageSize; i < len(matching) && i < (pageNumber+1)*pageSize; i++ {
+		data = append(data, matching[i])
+	}
+
+	pageLink := func(number string) string {
+		return fmt.Sprintf("%v?page%%5Bnumber%%5D=%v&page%%5Bsize%%5D=%v", strings.TrimSuffix(handler.uri, "/"), number, pageSize)
+	}
+	links := accountapi.Links{
+		"first": pageLink("first"),
+		"last":  pageLink("last"),
+		"self":  pageLink(strconv.Itoa(pageNumber)),
+	}
+	if pageNumber < lastPage {
+		links["next"] = pageLink(strconv.Itoa(pageNumber + 1))
+	}
+	if pageNumber > 0 {
+		links["prev"] = pageLink(strconv.Itoa(pageNumber - 1))
+	}
+
+	writeJSON(w, http.StatusOK, accountapi.Document[[]accountapi.Account]{Data: data, Links: links})
+}
+
+func (handler *Handler) delete(w http.ResponseWriter, r *http.Request, id string) {
+	version, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid version number")
+		return
+	}
+
+	if apiErr := handler.deleteAccount(id, version); apiErr != nil {
+		writeError(w, apiErr.StatusCode, apiErr.ErrorMessage)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// patch merges given attributes into the stored account when versions match
+func (handler *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		Data struct {
+			Version    *int                   `json:"version"`
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"data"`
+	}
+	bReqBody, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(bReqBody, &body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if body.Data.Version == nil {
+		writeError(w, http.StatusBadRequest, "validation failure list:\nversion in body is required")
+		return
+	}
+
+	patched, apiErr := handler.patchAccount(id, *body.Data.Version, body.Data.Attributes)
+	if apiErr != nil {
+		writeError(w, apiErr.StatusCode, apiErr.ErrorMessage)
+		return
+	}
+	handler.writeAccount(w, http.StatusOK, patched)
+}
+
+// matchesFilter reports whether account attributes match all filter[...] query parameters
+func matchesFilter(account accountapi.Account, query url.Values) bool {
+	var attributes map[string]interface{}
+	bAttributes, _ := json.Marshal(account.Attributes)
+	json.Unmarshal(bAttributes, &attributes)
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		name := key[len("filter[") : len(key)-1]
+		if fmt.Sprint(attributes[name]) != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// validate returns the list of validation failures in the bank's wording
+func validate(account accountapi.Account) []string {
+	var failures []string
+	if account.Type != "accounts" {
+		failures = append(failures, fmt.Sprintf("type in body should be one of [accounts]: %q", account.Type))
+	}
+	if !uuidPattern.MatchString(account.ID) {
+		failures = append(failures, fmt.Sprintf("id in body must be of type uuid: %q", account.ID))
+	}
+	if !uuidPattern.MatchString(account.OrganisationID) {
+		failures = append(failures, fmt.Sprintf("organisation_id in body must be of type uuid: %q", account.OrganisationID))
+	}
+	if len(account.Attributes.Country) != 2 {
+		failures = append(failures, "country in body should match '^[A-Z]{2}$'")
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+func (handler *Handler) writeAccount(w http.ResponseWriter, statusCode int, account accountapi.Account) {
+	writeJSON(w, statusCode, accountapi.Document[accountapi.Account]{
+		Data:  account,
+		Links: accountapi.Links{"self": handler.uri + account.ID},
+	})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, errorMessage string) {
+	writeJSON(w, statusCode, map[string]string{"error_message": errorMessage})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,155 @@
+package accountapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+const ukAccountID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+
+// newUkAccount returns a valid UK account with given ID
+func newUkAccount(id string) accountapi.Account {
+	account := accountapi.Account{Type: "accounts", ID: id, OrganisationID: "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"}
+	account.Attributes.Country = "GB"
+	account.Attributes.BaseCurrency = "GBP"
+	account.Attributes.BankID = "400300"
+	account.Attributes.BankIDCode = "GBDSC"
+	account.Attributes.Bic = "NWBKGB22"
+	return account
+}
+
+func doRequest(t *testing.T, method string, url string, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error while creating request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error while sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	bResponseBody, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(bResponseBody)
+}
+
+func TestServerLifecycle(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	config := server.Config()
+	account := newUkAccount(ukAccountID)
+
+	t.Run("Test Create and Fetch", func(t *testing.T) {
+		created, err := config.CreateAccount(account)
+		if err != nil {
+			t.Fatalf("Error while creating account: %v", err)
+		}
+		if created.CreatedOn.IsZero() || created.Version != 0 {
+			t.Errorf("Server managed fields are not set: %v", created)
+		}
+
+		fetched, err := config.FetchAccount(account.ID)
+		if err != nil || fetched.Attributes.Bic != account.Attributes.Bic {
+			t.Errorf("Fetched account does not match: %v %v", fetched, err)
+		}
+	})
+
+	t.Run("Test Duplicate Create Conflicts", func(t *testing.T) {
+		_, err := config.CreateAccount(account)
+		if err == nil || !strings.Contains(err.Error(), "duplicate constraint") {
+			t.Errorf("Expected duplicate constraint error, got: %v", err)
+		}
+	})
+
+	t.Run("Test Invalid Create", func(t *testing.T) {
+		invalid := newUkAccount("1234-abcd")
+		_, err := config.CreateAccount(invalid)
+		if err == nil || !strings.Contains(err.Error(), "id in body must be of type uuid") {
+			t.Errorf("Expected validation failure, got: %v", err)
+		}
+	})
+
+	t.Run("Test Patch", func(t *testing.T) {
+		url := config.AccountAPIUrl + account.ID
+		status, body := doRequest(t, "PATCH", url, `{"data":{"version":1,"attributes":{"bic":"NWBKGB33"}}}`)
+		if status != http.StatusConflict {
+			t.Errorf("Patch with stale version should conflict, got %v: %v", status, body)
+		}
+
+		status, body = doRequest(t, "PATCH", url, `{"data":{"version":0,"attributes":{"bic":"NWBKGB33","bank_id":null}}}`)
+		if status != http.StatusOK {
+			t.Fatalf("Patch failed with %v: %v", status, body)
+		}
+		patched, _ := config.FetchAccount(account.ID)
+		if patched.Version != 1 || patched.Attributes.Bic != "NWBKGB33" || patched.Attributes.BankID != "" ||
+			patched.Attributes.Country != "GB" {
+			t.Errorf("Patch was not merged: %v", patched)
+		}
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		if err := config.DeleteAccount(account.ID, 0); err == nil || err.Error() != "invalid version" {
+			t.Errorf("Delete with stale version should fail, got: %v", err)
+		}
+		if err := config.DeleteAccount(account.ID, 1); err != nil {
+			t.Errorf("Delete failed: %v", err)
+		}
+		_, err := config.FetchAccount(account.ID)
+		if err == nil || err.Error() != fmt.Sprintf("record %v does not exist", account.ID) {
+			t.Errorf("Deleted account should not exist, got: %v", err)
+		}
+	})
+}
+
+func TestServerPaging(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	for i := 0; i < 5; i++ {
+		server.Seed(newUkAccount(fmt.Sprintf("ad27e265-9605-4b4b-a0e5-3003ea9cc4d%v", i)))
+	}
+
+	listed, err := server.Config().ListAccounts(2, 2)
+	if err != nil || len(listed) != 1 || listed[0].ID != "ad27e265-9605-4b4b-a0e5-3003ea9cc4d4" {
+		t.Errorf("Last page does not match: %v %v", listed, err)
+	}
+
+	_, body := doRequest(t, "GET", server.URL+DefaultURI+"?page[number]=1&page[size]=2", "")
+	var page struct {
+		Data  []accountapi.Account `json:"data"`
+		Links map[string]string    `json:"links"`
+	}
+	if err := json.Unmarshal([]byte(body), &page); err != nil {
+		t.Fatalf("Error while parsing list response: %v", err)
+	}
+	if len(page.Data) != 2 || page.Links["next"] == "" || page.Links["prev"] == "" || page.Links["last"] == "" {
+		t.Errorf("Page is missing data or links: %v", body)
+	}
+}
+
+func TestServerHooks(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	config := server.Config()
+
+	server.FailNext(1, http.StatusInternalServerError, "injected failure")
+	if _, err := config.ListAccounts(0, 10); err == nil || err.Error() != "injected failure" {
+		t.Errorf("Expected injected failure, got: %v", err)
+	}
+	if _, err := config.ListAccounts(0, 10); err != nil {
+		t.Errorf("Failure should be injected only once: %v", err)
+	}
+
+	server.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	config.ListAccounts(0, 10)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Latency was not injected, took %v", elapsed)
+	}
+}
@@ -0,0 +1,184 @@
+package accountapitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// TestAccountServices runs the same operations against the HTTP fake and Memory
+func TestAccountServices(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	memory := NewMemory()
+
+	services := []struct {
+		name    string
+		service accountapi.AccountService
+		handler *Handler
+	}{
+		{"Server", server.Config(), server.Handler},
+		{"Memory", memory, memory.Handler},
+	}
+
+	for _, test := range services {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			service := test.service
+			account := newUkAccount(ukAccountID)
+
+			created, err := service.CreateAccountContext(ctx, account)
+			if err != nil || created.Version != 0 || created.CreatedOn.IsZero() {
+				t.Fatalf("Created account does not match: %+v %v", created, err)
+			}
+			var apiErr *accountapi.APIError
+			if _, err := service.CreateAccountContext(ctx, account); !errors.As(err, &apiErr) || !apiErr.IsConflict() {
+				t.Errorf("Duplicate account should conflict: %v", err)
+			}
+			invalid := newUkAccount("not-a-uuid")
+			if _, err := service.CreateAccountContext(ctx, invalid); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+				t.Errorf("Invalid account should be rejected: %v", err)
+			}
+
+			update := accountapi.Account{Type: "accounts", ID: account.ID}
+			update.Attributes.Bic = "NWBKGB42"
+			updated, err := service.UpdateAccountContext(ctx, update)
+			if err != nil || updated.Version != 1 || updated.Attributes.Bic != "NWBKGB42" || updated.Attributes.Country != "GB" {
+				t.Errorf("Only given attributes should change: %+v %v", updated, err)
+			}
+
+			fetched, err := service.FetchAccountContext(ctx, account.ID)
+			if err != nil || !reflect.DeepEqual(fetched.Attributes, updated.Attributes) {
+				t.Errorf("Fetched account does not match: %+v %v", fetched, err)
+			}
+
+			accounts, err := service.ListAccountsContext(ctx, 0, 10, accountapi.Filter{"country": "GB"})
+			if err != nil || len(accounts) != 1 {
+				t.Errorf("Filtered list does not match: %v %v", accounts, err)
+			}
+			if accounts, _ := service.ListAccountsContext(ctx, 0, 10, accountapi.Filter{"country": "NL"}); len(accounts) != 0 {
+				t.Errorf("Filter should exclude accounts: %v", accounts)
+			}
+
+			test.handler.FailNext(1, http.StatusServiceUnavailable, "injected failure")
+			if _, err := service.FetchAccountContext(ctx, account.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("Expected injected failure, got: %v", err)
+			}
+
+			if err := service.DeleteAccountContext(ctx, account.ID, 0); !errors.As(err, &apiErr) || !apiErr.IsConflict() {
+				t.Errorf("Stale version should not be deleted: %v", err)
+			}
+			if err := service.DeleteAccountContext(ctx, account.ID, 1); err != nil {
+				t.Errorf("Account should be deleted: %v", err)
+			}
+			if _, err := service.FetchAccountContext(ctx, account.ID); !errors.As(err, &apiErr) || !apiErr.IsNotFound() {
+				t.Errorf("Deleted account should not be found: %v", err)
+			}
+		})
+	}
+}
+
+func TestMock(t *testing.T) {
+	account := newUkAccount(ukAccountID)
+	unavailable := &accountapi.APIError{StatusCode: http.StatusServiceUnavailable}
+
+	tests := []struct {
+		name     string
+		expect   func(mock *Mock)
+		call     func(service accountapi.AccountService) error
+		expected []Call
+	}{
+		{
+			"Fetch",
+			func(mock *Mock) { mock.On(FetchAccount, account.ID).ReturnAccount(account, nil).Times(1) },
+			func(service accountapi.AccountService) error {
+				fetched, err := service.FetchAccountContext(context.Background(), account.ID)
+				if fetched.ID != account.ID {
+					return errors.New("fetched account does not match")
+				}
+				return err
+			},
+			[]Call{{FetchAccount, []interface{}{account.ID}}},
+		},
+		{
+			"List With Any Filter",
+			func(mock *Mock) {
+				mock.On(ListAccounts, 0, 100, Any).ReturnAccounts([]accountapi.Account{account}, nil)
+			},
+			func(service accountapi.AccountService) error {
+				service.ListAccountsContext(context.Background(), 0, 100, accountapi.Filter{"country": "GB"})
+				_, err := service.ListAccountsContext(context.Background(), 0, 100, nil)
+				return err
+			},
+			[]Call{
+				{ListAccounts, []interface{}{0, 100, accountapi.Filter{"country": "GB"}}},
+				{ListAccounts, []interface{}{0, 100, accountapi.Filter(nil)}},
+			},
+		},
+		{
+			"Retried Delete",
+			func(mock *Mock) {
+				mock.On(DeleteAccount, account.ID, 0).ReturnError(unavailable).Times(1)
+				mock.On(DeleteAccount, account.ID, 0).Times(1)
+			},
+			func(service accountapi.AccountService) error {
+				if err := service.DeleteAccountContext(context.Background(), account.ID, 0); err != unavailable {
+					return errors.New("first delete should fail")
+				}
+				return service.DeleteAccountContext(context.Background(), account.ID, 0)
+			},
+			[]Call{{DeleteAccount, []interface{}{account.ID, 0}}, {DeleteAccount, []interface{}{account.ID, 0}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock := NewMock(t)
+			test.expect(mock)
+			if err := test.call(mock); err != nil {
+				t.Errorf("Call failed: %v", err)
+			}
+			if calls := mock.Calls(); !reflect.DeepEqual(calls, test.expected) {
+				t.Errorf("Recorded calls do not match: %v", calls)
+			}
+			mock.AssertExpectations()
+		})
+	}
+}
+
+// errorRecorder records whether errors were reported, instead of failing the test
+type errorRecorder struct {
+	testing.TB
+	errors int
+}
+
+func (recorder *errorRecorder) Helper() {}
+
+func (recorder *errorRecorder) Errorf(format string, args ...interface{}) {
+	recorder.errors++
+}
+
+func TestMockUnexpectedCalls(t *testing.T) {
+	recorder := &errorRecorder{}
+	mock := NewMock(recorder)
+	mock.On(FetchAccount, ukAccountID).Times(1)
+	mock.On(UpdateAccount, Any)
+
+	mock.FetchAccountContext(context.Background(), ukAccountID)
+	if _, err := mock.FetchAccountContext(context.Background(), ukAccountID); err != ErrUnexpectedCall {
+		t.Errorf("Used up expectation should not match: %v", err)
+	}
+	mock.AssertExpectations()
+	// The second fetch was unexpected and the update was not made
+	if recorder.errors != 2 {
+		t.Errorf("Unexpected and missing calls should be reported")
+	}
+	if len(mock.CallsOf(FetchAccount)) != 2 || len(mock.CallsOf(UpdateAccount)) != 0 {
+		t.Errorf("Calls do not match: %v", mock.Calls())
+	}
+}
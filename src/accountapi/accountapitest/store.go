@@ -0,0 +1,144 @@
+package accountapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Operations on stored accounts, shared by the HTTP fake and Memory. Failures are the
+// errors the client would return for the responses the HTTP fake writes.
+
+func (handler *Handler) createAccount(account accountapi.Account) (accountapi.Account, *accountapi.APIError) {
+	if failures := validate(account); len(failures) > 0 {
+		return accountapi.Account{}, &accountapi.APIError{StatusCode: http.StatusBadRequest, ErrorMessage: "validation failure list:\n" + strings.Join(failures, "\n")}
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	if _, exists := handler.accounts[account.ID]; exists {
+		return accountapi.Account{}, &accountapi.APIError{StatusCode: http.StatusConflict, ErrorMessage: "Account cannot be created as it violates a duplicate constraint"}
+	}
+
+	now := accountapi.NewTimestamp(handler.now())
+	account.Version = 0
+	account.CreatedOn = now
+	account.ModifiedOn = now
+	handler.accounts[account.ID] = account
+	handler.order = append(handler.order, account.ID)
+	return account, nil
+}
+
+func (handler *Handler) fetchAccount(id string) (accountapi.Account, *accountapi.APIError) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	account, exists := handler.accounts[id]
+	if !exists {
+		return accountapi.Account{}, notFound(id)
+	}
+	return account, nil
+}
+
+// matchingAccounts returns accounts in creation order whose attributes match all
+// filter[...] query parameters, like filter[country]=NL, by their JSON name
+func (handler *Handler) matchingAccounts(query url.Values) []accountapi.Account {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	var matching []accountapi.Account
+	for _, id := range handler.order {
+		if matchesFilter(handler.accounts[id], query) {
+			matching = append(matching, handler.accounts[id])
+		}
+	}
+	return matching
+}
+
+func (handler *Handler) deleteAccount(id string, version int) *accountapi.APIError {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	account, exists := handler.accounts[id]
+	if !exists {
+		return notFound(id)
+	}
+	if account.Version != version {
+		return &accountapi.APIError{StatusCode: http.StatusConflict, ErrorMessage: "invalid version"}
+	}
+
+	delete(handler.accounts, id)
+	for i, orderedID := range handler.order {
+		if orderedID == id {
+			handler.order = append(handler.order[:i], handler.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// patchAccount merges given attributes into the stored account when versions match.
+// Attributes with nil values are removed.
+func (handler *Handler) patchAccount(id string, version int, changes map[string]interface{}) (accountapi.Account, *accountapi.APIError) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	account, exists := handler.accounts[id]
+	if !exists {
+		return accountapi.Account{}, notFound(id)
+	}
+	if account.Version != version {
+		return accountapi.Account{}, &accountapi.APIError{StatusCode: http.StatusConflict, ErrorMessage: "invalid version"}
+	}
+
+	// Merge attributes through their JSON form so that only given members change
+	var attributes map[string]interface{}
+	bAttributes, _ := json.Marshal(account.Attributes)
+	json.Unmarshal(bAttributes, &attributes)
+	for key, value := range changes {
+		if value == nil {
+			delete(attributes, key)
+		} else {
+			attributes[key] = value
+		}
+	}
+	bAttributes, _ = json.Marshal(attributes)
+
+	var merged accountapi.Account
+	if err := json.Unmarshal(bAttributes, &merged.Attributes); err != nil {
+		return accountapi.Account{}, &accountapi.APIError{StatusCode: http.StatusBadRequest, ErrorMessage: fmt.Sprintf("invalid attributes: %v", err)}
+	}
+	patched := account
+	patched.Attributes = merged.Attributes
+	if failures := validate(patched); len(failures) > 0 {
+		return accountapi.Account{}, &accountapi.APIError{StatusCode: http.StatusBadRequest, ErrorMessage: "validation failure list:\n" + strings.Join(failures, "\n")}
+	}
+
+	patched.Version = account.Version + 1
+	patched.ModifiedOn = accountapi.NewTimestamp(handler.now())
+	handler.accounts[id] = patched
+	return patched, nil
+}
+
+// nextFailure returns the failure to answer instead of handling a request, if any, and the latency to wait first
+func (handler *Handler) nextFailure() (*Failure, time.Duration) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	var failure *Failure
+	if len(handler.failures) > 0 {
+		failure = &handler.failures[0]
+		handler.failures = handler.failures[1:]
+	}
+	return failure, handler.latency
+}
+
+func notFound(id string) *accountapi.APIError {
+	return &accountapi.APIError{StatusCode: http.StatusNotFound, ErrorMessage: fmt.Sprintf("record %v does not exist", id)}
+}
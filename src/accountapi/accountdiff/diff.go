@@ -0,0 +1,168 @@
+// Package accountdiff compares accounts field by field. A diff is a typed change set that
+// tells which fields were added, removed or modified, renders as a readable list and converts
+// into the payload of a PATCH request.
+//
+//	changes := accountdiff.Diff(stored, wanted, accountdiff.Options{IgnoreServerManaged: true})
+//	if !changes.Empty() {
+//		fmt.Println(changes)
+//		patch, err := changes.Patch(stored)
+//		...
+//		config.Accounts().Patch(ctx, patch.ID, patch)
+//	}
+package accountdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// ServerManagedFields are set by the API, and differ between runs for the same account
+var ServerManagedFields = []string{"version", "created_on", "modified_on"}
+
+// Kind tells how a field changed
+type Kind string
+
+// Kinds of changes
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Modified Kind = "modified"
+)
+
+// Change of a single field. Field is the JSON path of the field, e.g. "attributes.bic".
+// Old and New hold values of the field type, the zero value when the field is not set.
+type Change struct {
+	Field string      `json:"field"`
+	Kind  Kind        `json:"kind"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// String renders the change as "+ field: new", "- field: old" or "~ field: old -> new"
+func (change Change) String() string {
+	switch change.Kind {
+	case Added:
+		return fmt.Sprintf("+ %v: %v", change.Field, render(change.New))
+	case Removed:
+		return fmt.Sprintf("- %v: %v", change.Field, render(change.Old))
+	}
+	return fmt.Sprintf("~ %v: %v -> %v", change.Field, render(change.Old), render(change.New))
+}
+
+// ChangeSet lists changes in field order of the Account struct
+type ChangeSet []Change
+
+// Empty reports whether the compared accounts are equal
+func (changes ChangeSet) Empty() bool {
+	return len(changes) == 0
+}
+
+// Fields returns paths of changed fields
+func (changes ChangeSet) Fields() []string {
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	return fields
+}
+
+// String renders one change per line, see Change.String
+func (changes ChangeSet) String() string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Options of Diff
+type Options struct {
+	// IgnoreServerManaged ignores ServerManagedFields, e.g. to compare a stored account with the one that was created
+	IgnoreServerManaged bool
+	// Ignore lists further fields to ignore by path, e.g. "attributes.status", or "attributes" for all attributes
+	Ignore []string
+}
+
+func (options Options) ignores(field string) bool {
+	ignored := options.Ignore
+	if options.IgnoreServerManaged {
+		ignored = append(ignored[:len(ignored):len(ignored)], ServerManagedFields...)
+	}
+	for _, path := range ignored {
+		if field == path || strings.HasPrefix(field, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns changes from old to new account. Unset fields and fields set to their zero value,
+// like empty lists, are equal, as the API does not tell them apart. Timestamps are compared as instants.
+func Diff(old accountapi.Account, new accountapi.Account, options Options) ChangeSet {
+	var changes ChangeSet
+	diffStruct("", reflect.ValueOf(old), reflect.ValueOf(new), options, &changes)
+	return changes
+}
+
+// Equal reports whether accounts do not differ in fields that are not ignored
+func Equal(a accountapi.Account, b accountapi.Account, options Options) bool {
+	return Diff(a, b, options).Empty()
+}
+
+var timestampType = reflect.TypeOf(accountapi.Timestamp{})
+
+func diffStruct(prefix string, old reflect.Value, new reflect.Value, options Options, changes *ChangeSet) {
+	for i := 0; i < old.NumField(); i++ {
+		structField := old.Type().Field(i)
+		name := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		field := prefix + name
+		if options.ignores(field) {
+			continue
+		}
+
+		oldValue, newValue := old.Field(i), new.Field(i)
+		if structField.Type.Kind() == reflect.Struct && structField.Type != timestampType {
+			diffStruct(field+".", oldValue, newValue, options, changes)
+			continue
+		}
+
+		change := Change{Field: field, Old: oldValue.Interface(), New: newValue.Interface()}
+		switch {
+		case equal(oldValue, newValue):
+			continue
+		case oldValue.IsZero() || oldValue.Kind() == reflect.Slice && oldValue.Len() == 0:
+			change.Kind = Added
+		case newValue.IsZero() || newValue.Kind() == reflect.Slice && newValue.Len() == 0:
+			change.Kind = Removed
+		default:
+			change.Kind = Modified
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+func equal(old reflect.Value, new reflect.Value) bool {
+	if old.Type() == timestampType {
+		return old.Interface().(accountapi.Timestamp).Equal(new.Interface().(accountapi.Timestamp))
+	}
+	if (old.Kind() == reflect.Slice || old.Kind() == reflect.Map) && old.Len() == 0 && new.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(old.Interface(), new.Interface())
+}
+
+// render formats a value as it appears in JSON
+func render(value interface{}) string {
+	bValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bValue)
+}
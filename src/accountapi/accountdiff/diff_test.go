@@ -0,0 +1,100 @@
+package accountdiff
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+)
+
+func newUkAccount() accountapi.Account {
+	account := accountapi.Account{Type: "accounts", ID: "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc", OrganisationID: "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"}
+	account.Attributes.Country = "GB"
+	account.Attributes.BaseCurrency = "GBP"
+	account.Attributes.BankID = "400300"
+	account.Attributes.BankIDCode = "GBDSC"
+	account.Attributes.Bic = "NWBKGB22"
+	account.Attributes.Status = "pending"
+	return account
+}
+
+func TestDiff(t *testing.T) {
+	old := newUkAccount()
+	old.Version = 1
+	old.ModifiedOn = accountapi.NewTimestamp(time.Now())
+
+	new := old
+	new.Version = 2
+	new.ModifiedOn = accountapi.NewTimestamp(time.Now().Add(time.Minute))
+	new.Attributes.Bic = "NWBKGB42"
+	new.Attributes.Name = []string{"Jane Doe"}
+	new.Attributes.Status = ""
+
+	changes := Diff(old, new, Options{})
+	expected := ChangeSet{
+		{"version", Modified, 1, 2},
+		{"modified_on", Modified, old.ModifiedOn, new.ModifiedOn},
+		{"attributes.bic", Modified, "NWBKGB22", "NWBKGB42"},
+		{"attributes.name", Added, []string(nil), []string{"Jane Doe"}},
+		{"attributes.status", Removed, "pending", ""},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Changes do not match:\n%v", changes)
+	}
+
+	changes = Diff(old, new, Options{IgnoreServerManaged: true, Ignore: []string{"attributes.name"}})
+	rendered := "~ attributes.bic: \"NWBKGB22\" -> \"NWBKGB42\"\n- attributes.status: \"pending\""
+	if changes.String() != rendered {
+		t.Errorf("Rendered changes do not match:\n%v", changes)
+	}
+
+	// Empty and unset lists, and timestamps formatted differently, are equal
+	new = old
+	new.Attributes.Name = []string{}
+	new.ModifiedOn, _ = accountapi.ParseTimestamp(old.ModifiedOn.Time.In(time.FixedZone("CET", 3600)).Format(time.RFC3339Nano))
+	if changes := Diff(old, new, Options{}); !changes.Empty() {
+		t.Errorf("Accounts should be equal:\n%v", changes)
+	}
+	if Equal(old, new, Options{Ignore: []string{"attributes"}}) != true {
+		t.Errorf("Ignored attributes should not be compared")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+
+	stored, err := config.CreateAccount(newUkAccount())
+	if err != nil {
+		t.Fatalf("Could not create account: %v", err)
+	}
+	wanted := stored
+	wanted.Attributes.Bic = "NWBKGB42"
+	wanted.Attributes.Status = ""
+
+	patch, err := Diff(stored, wanted, Options{}).Patch(stored)
+	if err != nil {
+		t.Fatalf("Could not convert changes: %v", err)
+	}
+	if !reflect.DeepEqual(patch.Attributes, map[string]interface{}{"bic": "NWBKGB42", "status": nil}) || patch.Version != stored.Version {
+		t.Errorf("Patch does not match: %+v", patch)
+	}
+
+	patched, err := config.Accounts().Patch(context.Background(), patch.ID, patch)
+	if err != nil {
+		t.Fatalf("Could not patch account: %v", err)
+	}
+	if changes := Diff(patched.Data, wanted, Options{IgnoreServerManaged: true}); !changes.Empty() {
+		t.Errorf("Patched account should be the wanted one:\n%v", changes)
+	}
+
+	moved := stored
+	moved.OrganisationID = "ee2fb143-6dfe-4787-b183-ca8ddd4164d2"
+	if _, err := Diff(stored, moved, Options{}).Patch(stored); err == nil {
+		t.Errorf("Organisation should not be patched")
+	}
+}
@@ -0,0 +1,39 @@
+package accountdiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Patch is the data of a PATCH request changing attributes of an account. Removed attributes
+// are sent as null, so unlike accountapi.UpdateAccount, a patch can unset attributes.
+type Patch struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Version    int                    `json:"version"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// Patch converts the changes into a patch of given account, the one the changes were made to.
+// Only attributes can be patched: changes of server managed fields are skipped, changes
+// of other fields, like the organisation, are an error.
+func (changes ChangeSet) Patch(account accountapi.Account) (Patch, error) {
+	patch := Patch{Type: account.Type, ID: account.ID, Version: account.Version, Attributes: map[string]interface{}{}}
+	for _, change := range changes {
+		if (Options{IgnoreServerManaged: true}).ignores(change.Field) {
+			continue
+		}
+		if !strings.HasPrefix(change.Field, "attributes.") {
+			return Patch{}, fmt.Errorf("%v can not be changed by a patch", change.Field)
+		}
+		attribute := strings.TrimPrefix(change.Field, "attributes.")
+		if change.Kind == Removed {
+			patch.Attributes[attribute] = nil
+		} else {
+			patch.Attributes[attribute] = change.New
+		}
+	}
+	return patch, nil
+}
@@ -0,0 +1,152 @@
+package accountio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+)
+
+// newAccount returns a valid NL account with given ID suffix
+func newAccount(i int) accountapi.Account {
+	account := accountapi.Account{
+		Type:           "accounts",
+		ID:             fmt.Sprintf("bf33e333-9605-4b4b-a0e5-3003ea9cc4d%v", i),
+		OrganisationID: "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c",
+	}
+	account.Attributes.Country = "NL"
+	account.Attributes.BaseCurrency = "EUR"
+	account.Attributes.Bic = "NLABNA01"
+	account.Attributes.Name = []string{"Jane Doe", "J, Doe"}
+	account.Attributes.JointAccount = true
+	return account
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewCSVWriter(&out, nil)
+	if err != nil {
+		t.Fatalf("Could not create writer: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := writer.Write(newAccount(i)); err != nil {
+			t.Fatalf("Could not write account: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Could not flush: %v", err)
+	}
+
+	reader, err := NewCSVReader(&out, nil)
+	if err != nil {
+		t.Fatalf("Could not create reader: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		account, err := reader.Read()
+		if err != nil || !reflect.DeepEqual(account, newAccount(i)) {
+			t.Errorf("Account %v does not match: %v %v", i, account, err)
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected end of input, got: %v", err)
+	}
+}
+
+func TestCSVColumnMapping(t *testing.T) {
+	input := "Account,Holders,Country,Comment\n" +
+		"bf33e333-9605-4b4b-a0e5-3003ea9cc4dc,Jane Doe|John Doe,NL,ignored\n"
+	columns := []Column{
+		{"Account", "id"},
+		{"Holders", "attributes.name"},
+		{"Country", "attributes.country"},
+	}
+
+	reader, err := NewCSVReader(strings.NewReader(input), columns)
+	if err != nil {
+		t.Fatalf("Could not create reader: %v", err)
+	}
+	reader.Separator = "|"
+	account, err := reader.Read()
+	if err != nil || account.ID != "bf33e333-9605-4b4b-a0e5-3003ea9cc4dc" || account.Attributes.Country != "NL" ||
+		!reflect.DeepEqual(account.Attributes.Name, []string{"Jane Doe", "John Doe"}) {
+		t.Errorf("Mapped account does not match: %v %v", account, err)
+	}
+
+	var out bytes.Buffer
+	writer, _ := NewCSVWriter(&out, columns[:2])
+	writer.Write(account)
+	writer.Flush()
+	if out.String() != "Account,Holders\nbf33e333-9605-4b4b-a0e5-3003ea9cc4dc,Jane Doe;John Doe\n" {
+		t.Errorf("Mapped output does not match: %q", out.String())
+	}
+
+	if _, err := NewCSVReader(strings.NewReader(input), []Column{{"Account", "iban"}}); err == nil {
+		t.Errorf("Unknown fields should be rejected")
+	}
+}
+
+func TestCSVInvalidValue(t *testing.T) {
+	reader, _ := NewCSVReader(strings.NewReader("id,attributes.joint_account\nabc,maybe\n"), nil)
+	if _, err := reader.Read(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected invalid value error, got: %v", err)
+	}
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewNDJSONWriter(&out)
+	writer.Write(newAccount(0))
+	writer.Write(newAccount(1))
+	writer.Flush()
+
+	if strings.Count(out.String(), "\n") != 2 {
+		t.Errorf("Expected one account per line: %v", out.String())
+	}
+
+	reader := NewNDJSONReader(&out)
+	for i := 0; i < 2; i++ {
+		account, err := reader.Read()
+		if err != nil || !reflect.DeepEqual(account, newAccount(i)) {
+			t.Errorf("Account %v does not match: %v %v", i, account, err)
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected end of input, got: %v", err)
+	}
+}
+
+func TestImportAndExport(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+
+	var input bytes.Buffer
+	writer := NewNDJSONWriter(&input)
+	for i := 0; i < 5; i++ {
+		writer.Write(newAccount(i))
+	}
+	writer.Flush()
+
+	var created int
+	err := Import(context.Background(), config, NewNDJSONReader(&input), 2, accountapi.BulkOptions{Concurrency: 2}, func(result accountapi.AccountResult) {
+		if result.Err == nil {
+			created++
+		}
+	})
+	if err != nil || created != 5 {
+		t.Errorf("Import created %v accounts: %v", created, err)
+	}
+
+	var out bytes.Buffer
+	csvWriter, _ := NewCSVWriter(&out, []Column{{"id", "id"}})
+	count, err := Export(config.IterateAccounts(context.Background(), 2, nil), csvWriter)
+	if err != nil || count != 5 || strings.Count(out.String(), "\n") != 6 {
+		t.Errorf("Export wrote %v accounts: %v\n%v", count, err, out.String())
+	}
+}
@@ -0,0 +1,125 @@
+package accountio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// DefaultSeparator joins list attributes such as names within a single CSV cell
+const DefaultSeparator = ";"
+
+// CSVWriter writes accounts as CSV rows, preceded by a header row
+type CSVWriter struct {
+	// Separator joins list attributes within a cell
+	Separator string
+
+	csv           *csv.Writer
+	columns       []Column
+	fields        []field
+	headerWritten bool
+}
+
+// NewCSVWriter returns a writer of given columns. All fields are written when columns is nil.
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	if columns == nil {
+		columns = DefaultColumns()
+	}
+	resolved, err := lookupFields(columns)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVWriter{Separator: DefaultSeparator, csv: csv.NewWriter(w), columns: columns, fields: resolved}, nil
+}
+
+// Write writes a single account, writing the header row first if it was not written yet
+func (writer *CSVWriter) Write(account accountapi.Account) error {
+	if !writer.headerWritten {
+		header := make([]string, len(writer.columns))
+		for i, column := range writer.columns {
+			header[i] = column.Header
+		}
+		if err := writer.csv.Write(header); err != nil {
+			return err
+		}
+		writer.headerWritten = true
+	}
+
+	record := make([]string, len(writer.fields))
+	for i, f := range writer.fields {
+		record[i] = f.get(&account, writer.Separator)
+	}
+	return writer.csv.Write(record)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (writer *CSVWriter) Flush() error {
+	writer.csv.Flush()
+	return writer.csv.Error()
+}
+
+// CSVReader reads accounts from CSV rows. The first row is a header, whose columns
+// are matched to fields by the configured column mapping; unmapped columns are ignored.
+type CSVReader struct {
+	// Separator splits list attributes within a cell
+	Separator string
+
+	csv     *csv.Reader
+	columns []Column
+	fields  []*field
+	line    int
+}
+
+// NewCSVReader returns a reader using given column mapping. When columns is nil,
+// headers are expected to be field names, as written with DefaultColumns.
+func NewCSVReader(r io.Reader, columns []Column) (*CSVReader, error) {
+	if columns == nil {
+		columns = DefaultColumns()
+	}
+	if _, err := lookupFields(columns); err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	return &CSVReader{Separator: DefaultSeparator, csv: reader, columns: columns}, nil
+}
+
+// Read returns the next account, or io.EOF when there are no more rows
+func (reader *CSVReader) Read() (accountapi.Account, error) {
+	var account accountapi.Account
+
+	if reader.fields == nil {
+		header, err := reader.csv.Read()
+		if err != nil {
+			return account, err
+		}
+		reader.line++
+		reader.fields = make([]*field, len(header))
+		for i, name := range header {
+			for _, column := range reader.columns {
+				if column.Header == name {
+					f := fields[column.Field]
+					reader.fields[i] = &f
+				}
+			}
+		}
+	}
+
+	record, err := reader.csv.Read()
+	if err != nil {
+		return account, err
+	}
+	reader.line++
+
+	for i, value := range record {
+		if i >= len(reader.fields) || reader.fields[i] == nil {
+			continue
+		}
+		if err := reader.fields[i].set(&account, value, reader.Separator); err != nil {
+			return account, fmt.Errorf("Invalid value %q on line %v: %v", value, reader.line, err)
+		}
+	}
+	return account, nil
+}
@@ -0,0 +1,160 @@
+// Package accountio streams accounts to and from CSV and newline-delimited JSON,
+// one account at a time, so that large files are never loaded into memory.
+package accountio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// field reads and writes a single Account field as text
+type field struct {
+	get func(account *accountapi.Account, separator string) string
+	set func(account *accountapi.Account, value string, separator string) error
+}
+
+func stringField(target func(account *accountapi.Account) *string) field {
+	return field{
+		func(account *accountapi.Account, _ string) string { return *target(account) },
+		func(account *accountapi.Account, value string, _ string) error {
+			*target(account) = value
+			return nil
+		},
+	}
+}
+
+// timestampField writes timestamps as the API sends them, e.g. "2021-01-09T13:24:54.567Z"
+func timestampField(target func(account *accountapi.Account) *accountapi.Timestamp) field {
+	return field{
+		func(account *accountapi.Account, _ string) string { return target(account).String() },
+		func(account *accountapi.Account, value string, _ string) error {
+			parsed, err := accountapi.ParseTimestamp(value)
+			*target(account) = parsed
+			return err
+		},
+	}
+}
+
+func boolField(target func(account *accountapi.Account) *bool) field {
+	return field{
+		func(account *accountapi.Account, _ string) string { return strconv.FormatBool(*target(account)) },
+		func(account *accountapi.Account, value string, _ string) error {
+			if value == "" {
+				*target(account) = false
+				return nil
+			}
+			parsed, err := strconv.ParseBool(value)
+			*target(account) = parsed
+			return err
+		},
+	}
+}
+
+// listField joins slices with the separator, e.g. "Jane Doe;J. Doe"
+func listField(target func(account *accountapi.Account) *[]string) field {
+	return field{
+		func(account *accountapi.Account, separator string) string {
+			return strings.Join(*target(account), separator)
+		},
+		func(account *accountapi.Account, value string, separator string) error {
+			if value == "" {
+				*target(account) = nil
+			} else {
+				*target(account) = strings.Split(value, separator)
+			}
+			return nil
+		},
+	}
+}
+
+// fields are keyed by the JSON names used by the API, with attributes prefixed by "attributes."
+var fields = map[string]field{
+	"type":            stringField(func(a *accountapi.Account) *string { return &a.Type }),
+	"id":              stringField(func(a *accountapi.Account) *string { return &a.ID }),
+	"organisation_id": stringField(func(a *accountapi.Account) *string { return &a.OrganisationID }),
+	"version": {
+		func(account *accountapi.Account, _ string) string { return strconv.Itoa(account.Version) },
+		func(account *accountapi.Account, value string, _ string) error {
+			if value == "" {
+				account.Version = 0
+				return nil
+			}
+			var err error
+			account.Version, err = strconv.Atoi(value)
+			return err
+		},
+	},
+	"created_on":                          timestampField(func(a *accountapi.Account) *accountapi.Timestamp { return &a.CreatedOn }),
+	"modified_on":                         timestampField(func(a *accountapi.Account) *accountapi.Timestamp { return &a.ModifiedOn }),
+	"attributes.country":                  stringField(func(a *accountapi.Account) *string { return &a.Attributes.Country }),
+	"attributes.base_currency":            stringField(func(a *accountapi.Account) *string { return &a.Attributes.BaseCurrency }),
+	"attributes.account_number":           stringField(func(a *accountapi.Account) *string { return &a.Attributes.AccountNumber }),
+	"attributes.bank_id":                  stringField(func(a *accountapi.Account) *string { return &a.Attributes.BankID }),
+	"attributes.bank_id_code":             stringField(func(a *accountapi.Account) *string { return &a.Attributes.BankIDCode }),
+	"attributes.bic":                      stringField(func(a *accountapi.Account) *string { return &a.Attributes.Bic }),
+	"attributes.iban":                     stringField(func(a *accountapi.Account) *string { return &a.Attributes.Iban }),
+	"attributes.name":                     listField(func(a *accountapi.Account) *[]string { return &a.Attributes.Name }),
+	"attributes.alternative_names":        listField(func(a *accountapi.Account) *[]string { return &a.Attributes.AlternativeNames }),
+	"attributes.account_classification":   stringField(func(a *accountapi.Account) *string { return &a.Attributes.AccountClassification }),
+	"attributes.joint_account":            boolField(func(a *accountapi.Account) *bool { return &a.Attributes.JointAccount }),
+	"attributes.account_matching_opt_out": boolField(func(a *accountapi.Account) *bool { return &a.Attributes.AccountMatchingOptOut }),
+	"attributes.secondary_identification": stringField(func(a *accountapi.Account) *string { return &a.Attributes.SecondaryIdentification }),
+	"attributes.switched":                 boolField(func(a *accountapi.Account) *bool { return &a.Attributes.Switched }),
+	"attributes.status":                   stringField(func(a *accountapi.Account) *string { return &a.Attributes.Status }),
+}
+
+// Fields lists all supported field names in the order of Account attributes
+var Fields = []string{
+	"type",
+	"id",
+	"organisation_id",
+	"version",
+	"created_on",
+	"modified_on",
+	"attributes.country",
+	"attributes.base_currency",
+	"attributes.account_number",
+	"attributes.bank_id",
+	"attributes.bank_id_code",
+	"attributes.bic",
+	"attributes.iban",
+	"attributes.name",
+	"attributes.alternative_names",
+	"attributes.account_classification",
+	"attributes.joint_account",
+	"attributes.account_matching_opt_out",
+	"attributes.secondary_identification",
+	"attributes.switched",
+	"attributes.status",
+}
+
+// Column maps a CSV column header to an Account field name, one of Fields
+type Column struct {
+	Header string
+	Field  string
+}
+
+// DefaultColumns returns a column for every field, named like the field
+func DefaultColumns() []Column {
+	columns := make([]Column, len(Fields))
+	for i, name := range Fields {
+		columns[i] = Column{name, name}
+	}
+	return columns
+}
+
+// lookupFields resolves field names of given columns
+func lookupFields(columns []Column) ([]field, error) {
+	resolved := make([]field, len(columns))
+	for i, column := range columns {
+		f, ok := fields[column.Field]
+		if !ok {
+			return nil, fmt.Errorf("Unknown field %q of column %q", column.Field, column.Header)
+		}
+		resolved[i] = f
+	}
+	return resolved, nil
+}
@@ -0,0 +1,48 @@
+package accountio
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// NDJSONWriter writes one account JSON document per line
+type NDJSONWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewNDJSONWriter returns a writer of newline-delimited JSON
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	buffer := bufio.NewWriter(w)
+	return &NDJSONWriter{buffer, json.NewEncoder(buffer)}
+}
+
+// Write writes a single account on its own line
+func (writer *NDJSONWriter) Write(account accountapi.Account) error {
+	return writer.encoder.Encode(account)
+}
+
+// Flush writes buffered lines to the underlying writer
+func (writer *NDJSONWriter) Flush() error {
+	return writer.buffer.Flush()
+}
+
+// NDJSONReader reads accounts from newline-delimited JSON
+type NDJSONReader struct {
+	decoder *json.Decoder
+}
+
+// NewNDJSONReader returns a reader of newline-delimited JSON
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{json.NewDecoder(r)}
+}
+
+// Read returns the next account, or io.EOF when there are no more lines
+func (reader *NDJSONReader) Read() (accountapi.Account, error) {
+	var account accountapi.Account
+	err := reader.decoder.Decode(&account)
+	return account, err
+}
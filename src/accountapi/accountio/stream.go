@@ -0,0 +1,77 @@
+package accountio
+
+import (
+	"context"
+	"io"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Writer is implemented by CSVWriter and NDJSONWriter
+type Writer interface {
+	Write(account accountapi.Account) error
+	Flush() error
+}
+
+// Reader is implemented by CSVReader and NDJSONReader. Read returns io.EOF at the end of input.
+type Reader interface {
+	Read() (accountapi.Account, error)
+}
+
+// Export writes all accounts of the iterator and returns how many were written
+func Export(it *accountapi.AccountIterator, writer Writer) (int, error) {
+	defer it.Close()
+	count := 0
+	for it.Next() {
+		if err := writer.Write(it.Account()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		writer.Flush()
+		return count, err
+	}
+	return count, writer.Flush()
+}
+
+// Import creates accounts read from reader, batchSize accounts at a time, so that only
+// a single batch is held in memory. Every result is passed to report, if given.
+// Import stops on the first read error, or on a create error when options.StopOnError is set.
+func Import(ctx context.Context, config accountapi.Configuration, reader Reader, batchSize int, options accountapi.BulkOptions, report func(accountapi.AccountResult)) error {
+	if batchSize < 1 {
+		batchSize = 100
+	}
+	batch := make([]accountapi.Account, 0, batchSize)
+
+	createBatch := func() error {
+		results, err := config.CreateAccounts(ctx, batch, options)
+		if report != nil {
+			for _, result := range results {
+				report(result)
+			}
+		}
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		account, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, account)
+		if len(batch) == batchSize {
+			if err = createBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return createBatch()
+	}
+	return nil
+}
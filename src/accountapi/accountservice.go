@@ -0,0 +1,19 @@
+package accountapi
+
+import "context"
+
+// AccountService is what code using accounts depends on, so that tests can substitute
+// Configuration with the in-memory implementation or the mock of package accountapitest.
+// Configuration and FailoverClient implement it.
+type AccountService interface {
+	CreateAccountContext(ctx context.Context, account Account) (Account, error)
+	FetchAccountContext(ctx context.Context, accountID string) (Account, error)
+	ListAccountsContext(ctx context.Context, pageNumber int, pageSize int, filter Filter) ([]Account, error)
+	UpdateAccountContext(ctx context.Context, account Account) (Account, error)
+	DeleteAccountContext(ctx context.Context, accountID string, version int) error
+}
+
+var (
+	_ AccountService = Configuration{}
+	_ AccountService = (*FailoverClient)(nil)
+)
@@ -0,0 +1,241 @@
+package accountapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownVersion can be passed to DeleteAccounts when the current version of an
+// account is not known. The version is then fetched before the account is deleted.
+const UnknownVersion = -1
+
+// MaxThrottledAttempts is how often an item of a bulk operation is sent while the API answers
+// 429 Too Many Requests
+const MaxThrottledAttempts = 5
+
+// ErrSkipped is reported for items of a bulk operation that were never sent,
+// because the operation stopped on an earlier error or its context was done.
+var ErrSkipped = errors.New("Skipped due to an earlier failure or cancellation")
+
+// BulkOptions configures how bulk operations fan out requests
+type BulkOptions struct {
+	// Concurrency is the maximum number of requests in flight. Values below 1 mean 1.
+	Concurrency int
+	// RequestsPerSecond limits how fast requests are started. Zero means no limit. Requests
+	// the API rejects with 429 Too Many Requests hold back all requests for the Retry-After
+	// delay, or a growing delay without it, and are sent again up to MaxThrottledAttempts times.
+	RequestsPerSecond float64
+	// StopOnError stops sending remaining items after the first failed one. Requests in flight
+	// are completed.
+	StopOnError bool
+}
+
+// AccountResult holds the outcome of a single item of a bulk operation
+type AccountResult struct {
+	Account Account
+	Err     error
+}
+
+// BulkResult aggregates outcome of a bulk operation keyed by account ID.
+// Partial failures are reported in Errors while succeeded items are kept in Accounts.
+type BulkResult struct {
+	Accounts map[string]Account
+	Errors   map[string]error
+}
+
+// Failed reports whether any item of the bulk operation failed
+func (result BulkResult) Failed() bool {
+	return len(result.Errors) > 0
+}
+
+// Err returns a single error describing all failed items, or nil if none failed
+func (result BulkResult) Err() error {
+	if !result.Failed() {
+		return nil
+	}
+	ids := make([]string, 0, len(result.Errors))
+	for id := range result.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	messages := make([]string, len(ids))
+	for i, id := range ids {
+		messages[i] = fmt.Sprintf("%v: %v", id, result.Errors[id])
+	}
+	return fmt.Errorf("%v of %v items failed: %v", len(ids), len(ids)+len(result.Accounts), strings.Join(messages, "; "))
+}
+
+// CreateAccounts creates given accounts using a pool of concurrent workers.
+// Returned results are in the same order as given accounts. The error is the first
+// failure when StopOnError is set, or the context error when ctx is done early.
+func (config Configuration) CreateAccounts(ctx context.Context, accounts []Account, options BulkOptions) ([]AccountResult, error) {
+	results := make([]AccountResult, len(accounts))
+
+	errs, err := runBulk(ctx, len(accounts), options, func(ctx context.Context, i int) error {
+		createdAccount, err := config.CreateAccountContext(ctx, accounts[i])
+		results[i].Account = createdAccount
+		return err
+	})
+
+	for i := range results {
+		results[i].Err = errs[i]
+	}
+
+	return results, err
+}
+
+// FetchAccounts fetches accounts with given IDs concurrently. Duplicate IDs are fetched once.
+func (config Configuration) FetchAccounts(ctx context.Context, accountIDs []string, options BulkOptions) (BulkResult, error) {
+	ids := uniqueIDs(accountIDs)
+	fetched := make([]Account, len(ids))
+
+	errs, err := runBulk(ctx, len(ids), options, func(ctx context.Context, i int) error {
+		var err error
+		fetched[i], err = config.FetchAccountContext(ctx, ids[i])
+		return err
+	})
+
+	return newBulkResult(ids, fetched, errs), err
+}
+
+// DeleteAccounts deletes accounts concurrently. The map holds account IDs and their versions;
+// for versions set to UnknownVersion the current version is fetched first.
+// Accounts of the result hold the deleted accounts as far as they are known.
+func (config Configuration) DeleteAccounts(ctx context.Context, versions map[string]int, options BulkOptions) (BulkResult, error) {
+	ids := make([]string, 0, len(versions))
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	deleted := make([]Account, len(ids))
+
+	errs, err := runBulk(ctx, len(ids), options, func(ctx context.Context, i int) error {
+		deleted[i] = Account{ID: ids[i], Version: versions[ids[i]]}
+		if deleted[i].Version == UnknownVersion {
+			current, err := config.FetchAccountContext(ctx, ids[i])
+			if err != nil {
+				return fmt.Errorf("Resolving version failed: %w", err)
+			}
+			deleted[i] = current
+		}
+		return config.DeleteAccountContext(ctx, ids[i], deleted[i].Version)
+	})
+
+	return newBulkResult(ids, deleted, errs), err
+}
+
+// newBulkResult splits per-item outcomes into succeeded accounts and errors
+func newBulkResult(ids []string, accounts []Account, errs []error) BulkResult {
+	result := BulkResult{map[string]Account{}, map[string]error{}}
+	for i, id := range ids {
+		if errs[i] != nil {
+			result.Errors[id] = errs[i]
+		} else {
+			result.Accounts[id] = accounts[i]
+		}
+	}
+	return result
+}
+
+// uniqueIDs removes duplicate IDs keeping the order of first occurrence
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var unique []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// doThrottled calls do for index i once the limiter allows it, and again while the API answers
+// 429 Too Many Requests, pausing the limiter for the delay it asked for. Items that could not
+// start before workCtx was done are skipped.
+func doThrottled(ctx context.Context, workCtx context.Context, limiter *rateLimiter, i int, do func(ctx context.Context, i int) error) error {
+	var err error
+	for attempts := 1; ; attempts++ {
+		if limiter.wait(workCtx) != nil {
+			if err != nil {
+				return err
+			}
+			return ErrSkipped
+		}
+		err = do(ctx, i)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || attempts >= MaxThrottledAttempts {
+			return err
+		}
+		delay := apiErr.RetryAfter
+		if delay <= 0 {
+			delay = RetryPolicy{}.Delay(attempts)
+		}
+		limiter.pause(delay)
+	}
+}
+
+// runBulk calls do for every index in [0, count) with bounded concurrency and returns
+// per-index errors. Indexes that were never attempted get ErrSkipped.
+func runBulk(ctx context.Context, count int, options BulkOptions, do func(ctx context.Context, i int) error) ([]error, error) {
+	errs := make([]error, count)
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Cancelling workCtx stops handing out items, requests in flight use ctx and are completed
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limiter := newRateLimiter(options.RequestsPerSecond)
+	defer limiter.stop()
+
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = doThrottled(ctx, workCtx, limiter, i, do)
+				if errs[i] != nil && errs[i] != ErrSkipped && options.StopOnError {
+					err := errs[i]
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	// Feed indexes to the workers until all are handed out or work is cancelled
+feed:
+	for i := 0; i < count; i++ {
+		select {
+		case indexes <- i:
+		case <-workCtx.Done():
+			for j := i; j < count; j++ {
+				errs[j] = ErrSkipped
+			}
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return errs, firstErr
+	}
+	return errs, ctx.Err()
+}
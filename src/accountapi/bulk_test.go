@@ -0,0 +1,285 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBulkTestServer echoes created accounts back and rejects invalidNlAccount.
+// It records the highest number of requests that were in flight at once.
+func newBulkTestServer(t *testing.T, maxInFlight *int32) *httptest.Server {
+	var inFlight int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var body Document[Account]
+		bReqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Error while reading test request body: %v", err)
+		}
+		json.Unmarshal(bReqBody, &body)
+
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if body.Data.ID == invalidNlAccount.ID {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error_message":"id in body must be of type uuid: \"1234-abcd\""}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Document[Account]{Data: body.Data})
+	}))
+}
+
+func TestCreateAccounts(t *testing.T) {
+	var maxInFlight int32
+	srv := newBulkTestServer(t, &maxInFlight)
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+	accounts := []Account{validUkAccount, invalidNlAccount, validNlAccount, validUkAccount, validNlAccount}
+
+	t.Run("Test Continue on Error", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		results, err := testConfig.CreateAccounts(context.Background(), accounts, BulkOptions{Concurrency: 2})
+		if err != nil {
+			t.Errorf("Bulk creation should not fail as a whole: %v", err)
+		}
+		if len(results) != len(accounts) {
+			t.Fatalf("Expected %v results, got %v", len(accounts), len(results))
+		}
+		for i, result := range results {
+			if accounts[i].ID == invalidNlAccount.ID {
+				if result.Err == nil {
+					t.Errorf("Item %v should have failed", i)
+				}
+				continue
+			}
+			if result.Err != nil || result.Account.ID != accounts[i].ID {
+				t.Errorf("Item %v was not created: %v", i, result.Err)
+			}
+		}
+		if atomic.LoadInt32(&maxInFlight) > 2 {
+			t.Errorf("Concurrency limit exceeded: %v requests in flight", maxInFlight)
+		}
+	})
+
+	t.Run("Test Stop on First Error", func(t *testing.T) {
+		results, err := testConfig.CreateAccounts(context.Background(), accounts, BulkOptions{Concurrency: 1, StopOnError: true})
+		if err == nil {
+			t.Errorf("Bulk creation should fail on invalid account")
+		}
+		if results[0].Err != nil {
+			t.Errorf("First account should be created: %v", results[0].Err)
+		}
+		for i := 2; i < len(results); i++ {
+			if results[i].Err != ErrSkipped {
+				t.Errorf("Item %v should be skipped, got: %v", i, results[i].Err)
+			}
+		}
+	})
+
+	t.Run("Test Rate Limit", func(t *testing.T) {
+		start := time.Now()
+		_, err := testConfig.CreateAccounts(context.Background(), accounts[2:], BulkOptions{Concurrency: 3, RequestsPerSecond: 20})
+		if err != nil {
+			t.Errorf("Bulk creation failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("Rate limit was not respected, took only %v", elapsed)
+		}
+	})
+
+	t.Run("Test Cancelled Context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := testConfig.CreateAccounts(ctx, accounts, BulkOptions{Concurrency: 2})
+		if err != context.Canceled {
+			t.Errorf("Expected context cancellation, got: %v", err)
+		}
+		for i, result := range results {
+			if result.Err == nil {
+				t.Errorf("Item %v should not be created", i)
+			}
+		}
+	})
+}
+
+func TestBulkStopAndThrottle(t *testing.T) {
+	t.Run("Test Stop Completes Requests in Flight", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body Document[Account]
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			if body.Data.ID == invalidNlAccount.ID {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(body)
+		}))
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		accounts := []Account{validUkAccount, invalidNlAccount, validNlAccount}
+		results, err := testConfig.CreateAccounts(context.Background(), accounts, BulkOptions{Concurrency: 2, StopOnError: true})
+		if err == nil || results[1].Err == nil {
+			t.Errorf("Bulk creation should fail on invalid account: %v", err)
+		}
+		if results[0].Err != nil || results[0].Account.ID != validUkAccount.ID {
+			t.Errorf("Request in flight when stopping should complete: %v", results[0].Err)
+		}
+		if results[2].Err != ErrSkipped {
+			t.Errorf("Item after the failure should be skipped, got: %v", results[2].Err)
+		}
+	})
+
+	t.Run("Test Retry-After", func(t *testing.T) {
+		var requests int32
+		throttled := map[string]bool{}
+		var mu sync.Mutex
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			var body Document[Account]
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			mu.Lock()
+			first := !throttled[body.Data.ID]
+			throttled[body.Data.ID] = true
+			mu.Unlock()
+			if first {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(body)
+		}))
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		start := time.Now()
+		results, err := testConfig.CreateAccounts(context.Background(), []Account{validUkAccount, validNlAccount}, BulkOptions{Concurrency: 2})
+		if err != nil || results[0].Err != nil || results[1].Err != nil {
+			t.Fatalf("Throttled items should be sent again: %v %+v", err, results)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("Retry-After was not respected, took only %v", elapsed)
+		}
+		if requests := atomic.LoadInt32(&requests); requests != 4 {
+			t.Errorf("Each item should be sent twice, got %v requests", requests)
+		}
+	})
+
+	t.Run("Test Parse Retry-After", func(t *testing.T) {
+		if delay := parseRetryAfter("120"); delay != 2*time.Minute {
+			t.Errorf("Delay in seconds does not match: %v", delay)
+		}
+		date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+		if delay := parseRetryAfter(date); delay <= 50*time.Second || delay > time.Minute {
+			t.Errorf("Delay until date does not match: %v", delay)
+		}
+		if delay := parseRetryAfter("soon"); delay != 0 {
+			t.Errorf("Invalid header should be no delay: %v", delay)
+		}
+	})
+}
+
+func TestFetchAndDeleteAccounts(t *testing.T) {
+	var fetches, deletes int32
+	testRouter := http.NewServeMux()
+
+	testRouter.HandleFunc("/v1/organisation/accounts/", func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[len("/v1/organisation/accounts/"):]
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+
+		if id != validUkAccount.ID && id != validNlAccount.ID {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_message":"record ` + id + ` does not exist"}`))
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			atomic.AddInt32(&fetches, 1)
+			account := validUkAccount
+			if id == validNlAccount.ID {
+				account = validNlAccount
+			}
+			account.Version = 3
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[Account]{Data: account})
+		case "DELETE":
+			atomic.AddInt32(&deletes, 1)
+			if r.URL.Query().Get("version") != "3" {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"error_message":"invalid version"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	srv := httptest.NewServer(testRouter)
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+	t.Run("Test Fetch Deduplicates IDs", func(t *testing.T) {
+		ids := []string{validUkAccount.ID, validNlAccount.ID, validUkAccount.ID, "missing"}
+		result, err := testConfig.FetchAccounts(context.Background(), ids, BulkOptions{Concurrency: 4})
+		if err != nil {
+			t.Errorf("Bulk fetch should not fail as a whole: %v", err)
+		}
+		if atomic.LoadInt32(&fetches) != 2 {
+			t.Errorf("Expected 2 fetches, got %v", fetches)
+		}
+		if len(result.Accounts) != 2 || result.Accounts[validNlAccount.ID].Attributes.Country != "NL" {
+			t.Errorf("Fetched accounts do not match: %v", result.Accounts)
+		}
+		if !result.Failed() || result.Errors["missing"] == nil {
+			t.Errorf("Fetching missing account should fail: %v", result.Errors)
+		}
+		if result.Err() == nil {
+			t.Errorf("Partial failure should be reported by Err")
+		}
+	})
+
+	t.Run("Test Delete Resolves Unknown Versions", func(t *testing.T) {
+		atomic.StoreInt32(&fetches, 0)
+		versions := map[string]int{validUkAccount.ID: UnknownVersion, validNlAccount.ID: 3}
+		result, err := testConfig.DeleteAccounts(context.Background(), versions, BulkOptions{Concurrency: 2})
+		if err != nil || result.Err() != nil {
+			t.Errorf("Bulk delete failed: %v %v", err, result.Err())
+		}
+		if atomic.LoadInt32(&fetches) != 1 || atomic.LoadInt32(&deletes) != 2 {
+			t.Errorf("Expected 1 fetch and 2 deletes, got %v and %v", fetches, deletes)
+		}
+		if result.Accounts[validUkAccount.ID].Version != 3 {
+			t.Errorf("Resolved version is not reported: %v", result.Accounts[validUkAccount.ID])
+		}
+	})
+
+	t.Run("Test Delete With Stale Version", func(t *testing.T) {
+		result, _ := testConfig.DeleteAccounts(context.Background(), map[string]int{validNlAccount.ID: 1}, BulkOptions{})
+		if result.Errors[validNlAccount.ID] == nil {
+			t.Errorf("Deleting stale version should fail")
+		}
+	})
+}
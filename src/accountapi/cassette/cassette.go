@@ -0,0 +1,237 @@
+// Package cassette provides an http.RoundTripper that records real request/response pairs
+// to golden files and replays them deterministically, so tests against the bank's API
+// can run without the API being available.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// Mode decides whether a Transport talks to the real API or replays a golden file
+type Mode int
+
+// Modes of a Transport
+const (
+	// Replay serves responses from the golden file and never touches the network
+	Replay Mode = iota
+	// Record forwards requests to the real API and stores the interactions
+	Record
+)
+
+// ScrubbedTimestamp replaces all timestamps in recorded bodies when timestamps are scrubbed
+const ScrubbedTimestamp = "1970-01-01T00:00:00.000Z"
+
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+var placeholderPattern = regexp.MustCompile(`00000000-0000-0000-0000-\d{12}`)
+
+// Interaction is a single recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds the parts of a request that are matched on replay
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response holds the recorded response
+type Response struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// Transport records or replays HTTP interactions stored in a golden file
+type Transport struct {
+	// Mode is either Record or Replay
+	Mode Mode
+	// Path of the golden file
+	Path string
+	// Next sends requests in Record mode. http.DefaultTransport is used when nil.
+	Next http.RoundTripper
+	// ScrubTimestamps replaces timestamps in recorded bodies with ScrubbedTimestamp
+	ScrubTimestamps bool
+	// ScrubIDs replaces UUIDs with placeholders numbered in order of appearance.
+	// On replay, placeholders are mapped back to the IDs of the replayed requests;
+	// IDs that only ever appear in responses stay as placeholders.
+	ScrubIDs bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	ids          map[string]string
+	placeholders map[string]string
+}
+
+// New returns a Transport for given golden file. In Replay mode the file is loaded and must exist.
+// Timestamps are scrubbed by default.
+func New(path string, mode Mode) (*Transport, error) {
+	transport := &Transport{Mode: mode, Path: path, ScrubTimestamps: true}
+	if mode == Replay {
+		bFile, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(bFile, &transport.interactions); err != nil {
+			return nil, fmt.Errorf("Invalid cassette %v: %v", path, err)
+		}
+		transport.used = make([]bool, len(transport.interactions))
+	}
+	return transport, nil
+}
+
+// ModeFromEnv returns Record when given environment variable is set to "record", else Replay
+func ModeFromEnv(key string) Mode {
+	if os.Getenv(key) == "record" {
+		return Record
+	}
+	return Replay
+}
+
+// RoundTrip records or replays a single request
+func (transport *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var bRequestBody []byte
+	if req.Body != nil {
+		var err error
+		bRequestBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bRequestBody))
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	recorded := Request{
+		Method: req.Method,
+		Path:   transport.scrub(req.URL.Path),
+		Query:  transport.scrub(req.URL.RawQuery),
+		Body:   transport.scrub(string(bRequestBody)),
+	}
+
+	if transport.Mode == Record {
+		return transport.record(req, recorded)
+	}
+	return transport.replay(req, recorded)
+}
+
+func (transport *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	next := transport.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bResponseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(bResponseBody))
+
+	transport.interactions = append(transport.interactions, Interaction{recorded, Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        transport.scrub(string(bResponseBody)),
+	}})
+	return resp, nil
+}
+
+// replay serves the first not yet used interaction that matches the request
+func (transport *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	for i, interaction := range transport.interactions {
+		if transport.used[i] || interaction.Request != recorded {
+			continue
+		}
+		transport.used[i] = true
+
+		body := interaction.Response.Body
+		if transport.ScrubIDs {
+			body = placeholderPattern.ReplaceAllStringFunc(body, func(placeholder string) string {
+				if id, ok := transport.placeholders[placeholder]; ok {
+					return id
+				}
+				return placeholder
+			})
+		}
+
+		header := http.Header{}
+		if interaction.Response.ContentType != "" {
+			header.Set("Content-Type", interaction.Response.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("No recorded interaction in %v matches %v %v?%v", transport.Path, recorded.Method, recorded.Path, recorded.Query)
+}
+
+// Save writes recorded interactions to the golden file. It does nothing in Replay mode.
+func (transport *Transport) Save() error {
+	if transport.Mode != Record {
+		return nil
+	}
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	bFile, err := json.MarshalIndent(transport.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(transport.Path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(transport.Path, append(bFile, '\n'), 0644)
+}
+
+// scrub replaces timestamps and IDs in given text according to the transport settings
+func (transport *Transport) scrub(text string) string {
+	if transport.ScrubTimestamps {
+		text = timestampPattern.ReplaceAllString(text, ScrubbedTimestamp)
+	}
+	if transport.ScrubIDs {
+		text = uuidPattern.ReplaceAllStringFunc(text, transport.placeholder)
+	}
+	return text
+}
+
+// placeholder returns a stable placeholder for given ID, allocating one on first use
+func (transport *Transport) placeholder(id string) string {
+	if transport.ids == nil {
+		transport.ids = map[string]string{}
+		transport.placeholders = map[string]string{}
+	}
+	if placeholder, ok := transport.ids[id]; ok {
+		return placeholder
+	}
+	placeholder := fmt.Sprintf("00000000-0000-0000-0000-%012d", len(transport.ids)+1)
+	transport.ids[id] = placeholder
+	transport.placeholders[placeholder] = id
+	return placeholder
+}
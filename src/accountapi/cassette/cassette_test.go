@@ -0,0 +1,97 @@
+package cassette
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const accountID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	bResponseBody, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(bResponseBody)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var served int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		id := strings.TrimPrefix(r.URL.Path, "/v1/organisation/accounts/")
+		if id != accountID {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error_message":"record %v does not exist"}`, id)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"id":"%v","created_on":"2021-01-09T13:24:54.567Z"}}`, id)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "fetch.json")
+
+	recorder, err := New(path, Record)
+	if err != nil {
+		t.Fatalf("Could not create recorder: %v", err)
+	}
+	recorder.ScrubIDs = true
+	client := &http.Client{Transport: recorder}
+	status, recordedBody := get(t, client, ts.URL+"/v1/organisation/accounts/"+accountID)
+	if status != http.StatusOK || !strings.Contains(recordedBody, "2021-01-09T13:24:54.567Z") {
+		t.Errorf("Recording should return real response, got %v: %v", status, recordedBody)
+	}
+	get(t, client, ts.URL+"/v1/organisation/accounts/missing")
+	if err = recorder.Save(); err != nil {
+		t.Fatalf("Could not save cassette: %v", err)
+	}
+
+	bFile, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(bFile), accountID) || strings.Contains(string(bFile), "2021-01-09") {
+		t.Errorf("Golden file is not scrubbed: %v", string(bFile))
+	}
+
+	player, err := New(path, Replay)
+	if err != nil {
+		t.Fatalf("Could not load cassette: %v", err)
+	}
+	player.ScrubIDs = true
+	client = &http.Client{Transport: player}
+
+	status, replayedBody := get(t, client, ts.URL+"/v1/organisation/accounts/"+accountID)
+	expected := strings.Replace(recordedBody, "2021-01-09T13:24:54.567Z", ScrubbedTimestamp, 1)
+	if status != http.StatusOK || replayedBody != expected {
+		t.Errorf("Replayed response does not match, got %v: %v", status, replayedBody)
+	}
+
+	status, _ = get(t, client, ts.URL+"/v1/organisation/accounts/missing")
+	if status != http.StatusNotFound {
+		t.Errorf("Replayed status does not match: %v", status)
+	}
+
+	if _, err = client.Get(ts.URL + "/v1/organisation/accounts/" + accountID); err == nil {
+		t.Errorf("Interactions should be replayed only once")
+	}
+	if served != 2 {
+		t.Errorf("Replay should not reach the server, it served %v requests", served)
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv("AccountAPICassette", "record")
+	if ModeFromEnv("AccountAPICassette") != Record {
+		t.Errorf("Expected Record mode")
+	}
+	t.Setenv("AccountAPICassette", "")
+	if ModeFromEnv("AccountAPICassette") != Replay {
+		t.Errorf("Expected Replay mode")
+	}
+}
@@ -0,0 +1,34 @@
+package accountapi
+
+// ClaimsPath is where account identification claim resources are served
+const ClaimsPath = "/v1/organisation/claims/"
+
+// Claim asks the Bank to assign account identification, e.g. an account number or IBAN,
+// to an organisation before an account is created with it
+type Claim struct {
+	Type           string          `json:"type"`
+	ID             string          `json:"id"`
+	OrganisationID string          `json:"organisation_id"`
+	Version        int             `json:"version,omitempty"`
+	CreatedOn      Timestamp       `json:"created_on,omitzero"`
+	ModifiedOn     Timestamp       `json:"modified_on,omitzero"`
+	Attributes     ClaimAttributes `json:"attributes"`
+}
+
+// ClaimAttributes hold the claimed identification. Status is set by the Bank,
+// e.g. "pending", "confirmed" or "rejected".
+type ClaimAttributes struct {
+	Country       string `json:"country"`
+	BankID        string `json:"bank_id,omitempty"`
+	BankIDCode    string `json:"bank_id_code,omitempty"`
+	Bic           string `json:"bic,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	Iban          string `json:"iban,omitempty"`
+	Status        string `json:"status,omitempty"`
+	StatusReason  string `json:"status_reason,omitempty"`
+}
+
+// Claims returns the client of account identification claim resources
+func (config Configuration) Claims() Resource[Claim] {
+	return NewResource[Claim](config, ClaimsPath)
+}
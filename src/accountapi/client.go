@@ -1,27 +1,44 @@
 package accountapi
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
+// defaultDialer opens connections of the shared client. Its timeouts keep connection attempts and
+// dead connections from hanging.
+var defaultDialer = &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+
 // Creating custom Transport to prevent connection hanging due to
-// default values being set to infinite
-var transport = &http.Transport{
-	// Limit the maximum number of idle (keep-alive) connections
-	MaxIdleConns: 10,
-	// Limit the maximum amount of time an idle
-	// (keep-alive) connection will remain idle before closing
-	IdleConnTimeout: 30 * time.Second,
-	// Limit the amount of time to wait for a server's response headers
-	// after fully writing the request
-	ResponseHeaderTimeout: 10 * time.Second,
-	// Limit the maximum amount of time to wait for a TLS handshake.
-	TLSHandshakeTimeout: 10 * time.Second,
+// default values being set to infinite. Proxies are taken from the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+var transport = newTransport(http.ProxyFromEnvironment, defaultDialer)
+
+// newTransport returns a transport sending requests through given proxy, over connections of given dialer
+func newTransport(proxy func(*http.Request) (*url.URL, error), dialer *net.Dialer) *http.Transport {
+	transport := &http.Transport{
+		Proxy:       proxy,
+		DialContext: dialer.DialContext,
+		// Limit the maximum number of idle (keep-alive) connections
+		MaxIdleConns: 10,
+		// Limit the maximum amount of time an idle
+		// (keep-alive) connection will remain idle before closing
+		IdleConnTimeout: 30 * time.Second,
+		// Limit the amount of time to wait for a server's response headers
+		// after fully writing the request
+		ResponseHeaderTimeout: 10 * time.Second,
+		// Limit the maximum amount of time to wait for a TLS handshake.
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	transport.RegisterProtocol(unixScheme, newUnixTransport(transport, dialer))
+	return transport
 }
 
 // Create global http client for shared use.
@@ -39,25 +56,50 @@ const (
 	POST   HTTPMethod = "POST"
 	GET               = "GET"
 	DELETE            = "DELETE"
+	PATCH             = "PATCH"
 )
 
-func doPost(baseURL string, bRequestBody []byte) ([]byte, error) {
-	return makeHTTPRequest(baseURL, POST, 201, bRequestBody, "")
+func (config Configuration) doPost(ctx context.Context, baseURL string, bRequestBody []byte) (io.ReadCloser, error) {
+	return config.openRequest(ctx, baseURL, POST, 201, bRequestBody, "")
+}
+
+func (config Configuration) doGet(ctx context.Context, baseURL string, queryParams string) (io.ReadCloser, error) {
+	return config.openRequest(ctx, baseURL, GET, 200, nil, queryParams)
 }
 
-func doGet(baseURL string, queryParams string) ([]byte, error) {
-	return makeHTTPRequest(baseURL, GET, 200, nil, queryParams)
+func (config Configuration) doPatch(ctx context.Context, baseURL string, bRequestBody []byte) (io.ReadCloser, error) {
+	return config.openRequest(ctx, baseURL, PATCH, 200, bRequestBody, "")
 }
 
-func doDelete(baseURL string, queryParams string) ([]byte, error) {
-	return makeHTTPRequest(baseURL, DELETE, 204, nil, queryParams)
+func (config Configuration) doDelete(ctx context.Context, baseURL string, queryParams string) (io.ReadCloser, error) {
+	return config.openRequest(ctx, baseURL, DELETE, 204, nil, queryParams)
+}
+
+// openRequest performs the request with the configured client and size limit, hedged when it is a GET
+// and a Hedger is configured, and returns the body of the successful response
+func (config Configuration) openRequest(ctx context.Context, baseURL string, method HTTPMethod, successStatusCode int, bRequestBody []byte, queryParams string) (io.ReadCloser, error) {
+	send := func(ctx context.Context) (io.ReadCloser, error) {
+		return openHTTPRequest(ctx, config.httpClient(), config.maxResponseBytes(), baseURL, method, successStatusCode, bRequestBody, queryParams)
+	}
+	if method == GET && config.Hedger != nil {
+		return config.Hedger.do(ctx, send)
+	}
+	return send(ctx)
 }
 
 func makeHTTPRequest(baseURL string, method HTTPMethod, successStatusCode int, bRequestBody []byte, queryParams string) ([]byte, error) {
-	var resp *http.Response
-	var err error
-	var bResponseBody []byte
+	body, err := openHTTPRequest(context.Background(), netClient, DefaultMaxResponseBytes, baseURL, method, successStatusCode, bRequestBody, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
 
+// openHTTPRequest performs the request with given client and cancels it when given context is done.
+// It returns the body of a successful response, which the caller closes, or an *APIError built from
+// the body of any other response. Reading more than maxBytes of a body fails with a *ResponseTooLargeError.
+func openHTTPRequest(ctx context.Context, client *http.Client, maxBytes int64, baseURL string, method HTTPMethod, successStatusCode int, bRequestBody []byte, queryParams string) (io.ReadCloser, error) {
 	// Prepare request body in case it's a Post.
 	reqBody := strings.NewReader(string(bRequestBody))
 
@@ -66,50 +108,68 @@ func makeHTTPRequest(baseURL string, method HTTPMethod, successStatusCode int, b
 		baseURL = baseURL + queryParams
 	}
 
-	switch method {
-	case POST:
-		resp, err = netClient.Post(baseURL, "application/vnd.api+json", reqBody)
-	case GET:
-		resp, err = netClient.Get(baseURL)
-	case DELETE:
-		req, err := http.NewRequest("DELETE", baseURL, nil)
-		if err != nil {
-			return bResponseBody, err
-		}
-		resp, err = netClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, string(method), baseURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if method == POST || method == PATCH {
+		req.Header.Set("Content-Type", "application/vnd.api+json")
 	}
 
+	resp, err := client.Do(req)
 	if err != nil {
-		//log.Printf("Failed to access AccountAPI endpoint: %v", err)
-		return bResponseBody, err
+		return nil, err
 	}
 
-	// Defer closing the stream
-	defer resp.Body.Close()
-	// Try to read response body (if not nil) to see the response
-	bResponseBody, err = ioutil.ReadAll(resp.Body)
-
-	if err != nil {
-		//log.Printf("Failed to parse AccountAPI response: %v", err)
-		return bResponseBody, err
+	tooLarge := &ResponseTooLargeError{URL: baseURL, Limit: maxBytes}
+	if resp.ContentLength > maxBytes {
+		resp.Body.Close()
+		return nil, tooLarge
 	}
+	body := &limitedBody{ReadCloser: resp.Body, remaining: maxBytes, tooLarge: tooLarge}
 
 	if resp.StatusCode != successStatusCode {
-		// If not success, then get verbose response error from the body
+		defer body.Close()
+		bResponseBody, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		// If not success, then get verbose response error from the body.
+		// If it can not be parsed, it was a silent failure and the message stays empty,
+		// assuming that API will always return error in same format.
 		var resErr responseErr
-		// With side effect, probably not ideal
-		err = json.Unmarshal(bResponseBody, &resErr)
-
-		// If parsing of error response succeeds, and is non-empty, we return that.
-		// Else, it was a silent failure, assuming that API will always return error in same format.
-		if err == nil {
-			if resErr.ErrorMessage != "" {
-				err = fmt.Errorf(resErr.ErrorMessage)
-			} else {
-				err = fmt.Errorf("Request silently failed with status code %v", resp.StatusCode)
-			}
+		json.Unmarshal(bResponseBody, &resErr)
+		return nil, &APIError{
+			StatusCode:   resp.StatusCode,
+			ErrorMessage: resErr.ErrorMessage,
+			Errors:       resErr.Errors,
+			RetryAfter:   parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
 	}
+	return body, nil
+}
+
+// limitedBody fails with tooLarge once more than the remaining bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	tooLarge  *ResponseTooLargeError
+}
 
-	return bResponseBody, err
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, body.tooLarge
+	}
+	// Reading one byte more than allowed tells whether the body goes on
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+	n, err := body.ReadCloser.Read(p)
+	if int64(n) <= body.remaining {
+		body.remaining -= int64(n)
+		return n, err
+	}
+	n = int(body.remaining)
+	body.remaining = -1
+	return n, body.tooLarge
 }
@@ -1,16 +1,20 @@
 package accountapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
 	"testing"
 )
 
 func TestGetWithMockHTTPServer(t *testing.T) {
-	var mockedResponse sliceResponseBody
+	var mockedResponse Document[[]Account]
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -99,7 +103,7 @@ func TestGetWithNonexistingIdWithMockHTTPServer(t *testing.T) {
 
 func TestPostWithMockHTTPServer(t *testing.T) {
 	var createdAccount Account
-	var mockedResponse responseBody
+	var mockedResponse Document[Account]
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
@@ -136,7 +140,7 @@ func TestPostWithMockHTTPServer(t *testing.T) {
 
 func TestListWithMockHTTPServer(t *testing.T) {
 	var createdAccount Account
-	var mockedResponse sliceResponseBody
+	var mockedResponse Document[[]Account]
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -186,3 +190,85 @@ func TestDeleteWithMockHTTPServer(t *testing.T) {
 	}
 
 }
+
+func TestAPIErrorWithMockHTTPServer(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintln(w, `<html>Conflict</html>`)
+	}))
+	defer ts.Close()
+
+	_, err := makeHTTPRequest(ts.URL, DELETE, 204, nil, "?version=0")
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Expected APIError, got: %v", err)
+	}
+	if !apiErr.IsConflict() || apiErr.IsNotFound() {
+		t.Errorf("Unexpected status code: %v", apiErr.StatusCode)
+	}
+	if apiErr.Error() != "Request silently failed with status code 409" {
+		t.Errorf("Unexpected error message: %v", apiErr)
+	}
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	var status int32 = http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body Document[[]Account]
+		for i := 0; i < 40; i++ {
+			body.Data = append(body.Data, validUkAccount)
+		}
+		bBody, _ := json.Marshal(body)
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if r.URL.Query().Get("filter[length]") != "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(bBody)))
+		}
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write(bBody)
+	}))
+	defer ts.Close()
+	testConfig := Configuration{AccountAPIUrl: ts.URL + "/v1/organisation/accounts/", MaxResponseBytes: 1024}
+
+	t.Run("Test Streamed Body", func(t *testing.T) {
+		it := testConfig.IterateAccounts(context.Background(), 40, nil)
+		count := 0
+		for it.Next() {
+			count++
+		}
+		var tooLarge *ResponseTooLargeError
+		if !errors.As(it.Err(), &tooLarge) || tooLarge.Limit != 1024 {
+			t.Errorf("Iteration should stop at the limit: %v", it.Err())
+		}
+		if count == 0 || count == 40 {
+			t.Errorf("Accounts within the limit should be iterated: %v", count)
+		}
+	})
+
+	t.Run("Test Declared Length", func(t *testing.T) {
+		_, err := testConfig.ListAccountsContext(context.Background(), 0, 40, Filter{"length": "declared"})
+		var tooLarge *ResponseTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("Declared length should be rejected: %v", err)
+		}
+	})
+
+	t.Run("Test Error Response", func(t *testing.T) {
+		atomic.StoreInt32(&status, http.StatusInternalServerError)
+		defer atomic.StoreInt32(&status, http.StatusOK)
+		_, err := testConfig.FetchAccountContext(context.Background(), validUkAccount.ID)
+		var tooLarge *ResponseTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("Large error response should be rejected: %v", err)
+		}
+	})
+
+	t.Run("Test Default Limit", func(t *testing.T) {
+		testConfig.MaxResponseBytes = 0
+		if accounts, err := testConfig.ListAccountsContext(context.Background(), 0, 40, nil); err != nil || len(accounts) != 40 {
+			t.Errorf("Response within the default limit should be read: %v", err)
+		}
+	})
+}
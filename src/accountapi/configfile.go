@@ -0,0 +1,112 @@
+package accountapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile holds top-level settings and named profiles of a configuration file
+type configFile struct {
+	settings map[string]string
+	profiles map[string]map[string]string
+}
+
+// readConfigFile parses the file by its extension and checks that only known settings are used
+func readConfigFile(path string) (configFile, error) {
+	file := configFile{map[string]string{}, map[string]map[string]string{}}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		document, err = parseYAML(data)
+	case ".json":
+		document, err = parseJSON(data)
+	case ".toml":
+		document, err = parseTOML(data)
+	default:
+		err = fmt.Errorf("unsupported format, use .yaml, .json or .toml")
+	}
+	if err != nil {
+		return file, fmt.Errorf("Invalid configuration file %v: %v", path, err)
+	}
+
+	for key, value := range document {
+		if key == "profiles" {
+			profiles, ok := value.(map[string]interface{})
+			if !ok {
+				return file, fmt.Errorf("Invalid configuration file %v: profiles must be a mapping", path)
+			}
+			for name, profile := range profiles {
+				profileDocument, ok := profile.(map[string]interface{})
+				if !ok {
+					return file, fmt.Errorf("Invalid configuration file %v: profile %v must be a mapping", path, name)
+				}
+				file.profiles[name] = map[string]string{}
+				if err = collectSettings(profileDocument, file.profiles[name]); err != nil {
+					return file, fmt.Errorf("Invalid configuration file %v: profile %v: %v", path, name, err)
+				}
+			}
+			delete(document, key)
+		}
+	}
+	if err = collectSettings(document, file.settings); err != nil {
+		return file, fmt.Errorf("Invalid configuration file %v: %v", path, err)
+	}
+	return file, nil
+}
+
+// collectSettings copies known scalar settings from document to target
+func collectSettings(document map[string]interface{}, target map[string]string) error {
+	for key, value := range document {
+		if _, known := settings[key]; !known {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("setting %q must be a scalar", key)
+		case nil:
+			return fmt.Errorf("setting %q has no value", key)
+		}
+		target[key] = fmt.Sprint(value)
+	}
+	return nil
+}
+
+// parseJSON parses a JSON object
+func parseJSON(data []byte) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&document)
+	return document, err
+}
+
+// parseYAML parses a YAML mapping
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// parseTOML parses a TOML document, where profiles are tables like [profiles.dev]
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := toml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
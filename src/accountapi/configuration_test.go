@@ -0,0 +1,168 @@
+package accountapi
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearEnv makes sure environment of the test runner does not leak into loaded configuration
+func clearEnv(t *testing.T) {
+	for _, suffix := range []string{"Protocol", "Socket", "Uri", "Url", "Proxy", "Profile"} {
+		t.Setenv(DefaultEnvPrefix+suffix, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not write configuration file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+
+	loaded, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("Could not load defaults: %v", err)
+	}
+	expected := Configuration{
+		AccountAPIProtocol: "http://",
+		AccountAPISocket:   "localhost:8080",
+		AccountAPIUri:      "/v1/organisation/accounts/",
+		AccountAPIUrl:      "http://localhost:8080/v1/organisation/accounts/",
+	}
+	if loaded != expected {
+		t.Errorf("Loaded configuration does not match: %+v", loaded)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `# Shared settings
+protocol: https://
+uri: "/v1/organisation/accounts/"
+profiles:
+  dev:
+    protocol: http://
+    socket: localhost:8080 # local mock
+  prod:
+    socket: 'api.bank.example:443'
+`,
+		"config.json": `{
+  "protocol": "https://",
+  "uri": "/v1/organisation/accounts/",
+  "profiles": {
+    "dev": {"protocol": "http://", "socket": "localhost:8080"},
+    "prod": {"socket": "api.bank.example:443"}
+  }
+}`,
+		"config.toml": `protocol = "https://"
+uri = "/v1/organisation/accounts/"
+
+[profiles.dev]
+protocol = "http://"
+socket = "localhost:8080" # local mock
+
+[profiles.prod]
+socket = "api.bank.example:443"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			path := writeConfigFile(t, name, content)
+
+			dev, err := Load(LoadOptions{File: path, Profile: "dev"})
+			if err != nil || dev.AccountAPIUrl != "http://localhost:8080/v1/organisation/accounts/" {
+				t.Errorf("Dev profile does not match: %+v %v", dev, err)
+			}
+
+			t.Setenv("AccountAPIProfile", "prod")
+			prod, err := Load(LoadOptions{File: path})
+			if err != nil || prod.AccountAPIUrl != "https://api.bank.example:443/v1/organisation/accounts/" ||
+				prod.AccountAPIProtocol != "https://" {
+				t.Errorf("Prod profile does not match: %+v %v", prod, err)
+			}
+
+			if _, err := Load(LoadOptions{File: path, Profile: "staging"}); err == nil {
+				t.Errorf("Undefined profile should fail")
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	clearEnv(t)
+	path := writeConfigFile(t, "config.yaml", "profiles:\n  dev:\n    socket: localhost:8080\n")
+
+	t.Setenv("BankSocket", "bank.internal:9090")
+	loaded, err := Load(LoadOptions{File: path, Profile: "dev", EnvPrefix: "Bank"})
+	if err != nil || loaded.AccountAPIUrl != "http://bank.internal:9090/v1/organisation/accounts/" {
+		t.Errorf("Environment override does not match: %+v %v", loaded, err)
+	}
+
+	t.Setenv("BankSocket", "")
+	t.Setenv("BankUrl", "https://bank.example/accounts/")
+	loaded, err = Load(LoadOptions{File: path, Profile: "dev", EnvPrefix: "Bank"})
+	if err != nil || loaded.AccountAPIUrl != "https://bank.example/accounts/" {
+		t.Errorf("URL override does not match: %+v %v", loaded, err)
+	}
+}
+
+func TestLoadURLParts(t *testing.T) {
+	clearEnv(t)
+	path := writeConfigFile(t, "config.toml", "url = \"https://gw.example/bank/v1/organisation/accounts/\"\n\n[profiles.dev]\nprotocol = \"http://\"\n")
+
+	t.Setenv("AccountAPISocket", "localhost:8080")
+	loaded, err := Load(LoadOptions{File: path})
+	if err != nil || loaded.AccountAPIUrl != "https://localhost:8080/bank/v1/organisation/accounts/" {
+		t.Errorf("Socket from environment should override the host of the url: %+v %v", loaded, err)
+	}
+	loaded, err = Load(LoadOptions{File: path, Profile: "dev"})
+	if err != nil || loaded.AccountAPIUrl != "http://localhost:8080/bank/v1/organisation/accounts/" {
+		t.Errorf("Protocol of the profile should override the scheme of the url: %+v %v", loaded, err)
+	}
+
+	t.Setenv("AccountAPISocket", "")
+	t.Setenv("AccountAPIUri", "/v2/accounts/")
+	t.Setenv("AccountAPIUrl", "unix:///var/run/accountapi.sock:/v1/organisation/accounts/")
+	if _, err := Load(LoadOptions{File: path}); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Url and its parts in the same source should fail, got: %v", err)
+	}
+	t.Setenv("AccountAPIUri", "")
+	loaded, err = Load(LoadOptions{File: path})
+	if err != nil || loaded.AccountAPIUrl != "unix:///var/run/accountapi.sock:/v1/organisation/accounts/" ||
+		loaded.AccountAPISocket != "/var/run/accountapi.sock" {
+		t.Errorf("Unix url should be split into its parts: %+v %v", loaded, err)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	clearEnv(t)
+
+	t.Setenv("AccountAPIProtocol", "ftp://")
+	if _, err := Load(LoadOptions{}); err == nil || !strings.Contains(err.Error(), "scheme") {
+		t.Errorf("Invalid scheme should fail, got: %v", err)
+	}
+
+	t.Setenv("AccountAPIProtocol", "")
+	t.Setenv("AccountAPIUri", "/v1/organisation/accounts")
+	if _, err := Load(LoadOptions{}); err == nil {
+		t.Errorf("URI without trailing slash should fail")
+	}
+
+	path := writeConfigFile(t, "config.yaml", "timeout: 10s\n")
+	if _, err := Load(LoadOptions{File: path}); err == nil || !strings.Contains(err.Error(), "unknown setting") {
+		t.Errorf("Unknown setting should fail, got: %v", err)
+	}
+
+	path = writeConfigFile(t, "config.ini", "socket=localhost")
+	if _, err := Load(LoadOptions{File: path}); err == nil {
+		t.Errorf("Unsupported format should fail")
+	}
+}
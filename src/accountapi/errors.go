@@ -1,6 +1,74 @@
 package accountapi
 
-// ResponseErr is an expected error message body format
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseErr is an expected error message body format. The API sends error_message,
+// other JSON:API servers send a list of error objects.
 type responseErr struct {
-	ErrorMessage string `json:"error_message"`
+	ErrorMessage string        `json:"error_message"`
+	Errors       []ErrorObject `json:"errors"`
+}
+
+// APIError is returned when the API responds with a status code other than the expected one.
+// Its message is the error_message of the response, or the JSON:API errors, if the API sent any.
+type APIError struct {
+	StatusCode   int
+	ErrorMessage string
+	Errors       []ErrorObject
+	// RetryAfter is the delay the API asked for with a Retry-After header, zero when it sent none
+	RetryAfter time.Duration
+}
+
+func (err *APIError) Error() string {
+	if err.ErrorMessage != "" {
+		return err.ErrorMessage
+	}
+	if len(err.Errors) > 0 {
+		messages := make([]string, len(err.Errors))
+		for i, object := range err.Errors {
+			messages[i] = object.String()
+		}
+		return strings.Join(messages, "; ")
+	}
+	return fmt.Sprintf("Request silently failed with status code %v", err.StatusCode)
+}
+
+// IsNotFound reports whether the requested resource does not exist
+func (err *APIError) IsNotFound() bool {
+	return err.StatusCode == 404
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// IsConflict reports whether the request conflicts with the current state, e.g. a stale version
+func (err *APIError) IsConflict() bool {
+	return err.StatusCode == 409
+}
+
+// ResponseTooLargeError is returned when a response body is larger than the configured
+// MaxResponseBytes. Fetches and page lists return nothing decoded from the body with it, but
+// AccountIterator streams pages: it yields the accounts read before the limit was reached,
+// and Err returns the error after them.
+type ResponseTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (err *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("Response of %v exceeds the limit of %v bytes", err.URL, err.Limit)
 }
@@ -0,0 +1,225 @@
+package accountapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is how long a failed endpoint is avoided before it is tried first again
+const DefaultCooldown = 30 * time.Second
+
+// FailoverOptions configure a FailoverClient
+type FailoverOptions struct {
+	// URLs are base URLs of the API in order of preference, the first one is the primary
+	URLs []string
+	// HTTPClient is used to send requests to all endpoints. The shared client is used when nil.
+	HTTPClient *http.Client
+	// AllowWriteFailover sends creates, updates and deletes to secondary endpoints when the primary fails.
+	// By default writes are only sent to the primary.
+	AllowWriteFailover bool
+	// Cooldown overrides DefaultCooldown
+	Cooldown time.Duration
+}
+
+// EndpointStatus reports health of a single endpoint
+type EndpointStatus struct {
+	URL       string
+	Healthy   bool
+	LastError error
+}
+
+// Route reports which endpoints a call was sent to
+type Route struct {
+	// Endpoint is the base URL that served the call, empty when all attempts failed
+	Endpoint string
+	// Failed lists base URLs that were tried before, in order
+	Failed []string
+}
+
+type routeKey struct{}
+
+// WithRoute returns a context that records the route of calls made with it into route
+func WithRoute(ctx context.Context, route *Route) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// FailoverClient sends calls to the first healthy of several endpoints. Reads fail over to the next
+// endpoint on connection errors and 5xx responses, other errors are returned as they are.
+type FailoverClient struct {
+	endpoints          []*endpoint
+	allowWriteFailover bool
+	cooldown           time.Duration
+	now                func() time.Time
+}
+
+type endpoint struct {
+	config Configuration
+
+	mu        sync.Mutex
+	downUntil time.Time
+	lastError error
+}
+
+// NewFailoverClient validates the URLs and returns a client using them
+func NewFailoverClient(options FailoverOptions) (*FailoverClient, error) {
+	if len(options.URLs) == 0 {
+		return nil, fmt.Errorf("At least one URL is required")
+	}
+	client := &FailoverClient{
+		allowWriteFailover: options.AllowWriteFailover,
+		cooldown:           options.Cooldown,
+		now:                time.Now,
+	}
+	if client.cooldown <= 0 {
+		client.cooldown = DefaultCooldown
+	}
+	for _, baseURL := range options.URLs {
+		config := Configuration{AccountAPIUrl: baseURL, HTTPClient: options.HTTPClient}
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+		client.endpoints = append(client.endpoints, &endpoint{config: config})
+	}
+	return client, nil
+}
+
+// Status reports health of all endpoints in order of preference
+func (client *FailoverClient) Status() []EndpointStatus {
+	now := client.now()
+	var statuses []EndpointStatus
+	for _, endpoint := range client.endpoints {
+		endpoint.mu.Lock()
+		statuses = append(statuses, EndpointStatus{
+			URL:       endpoint.config.AccountAPIUrl,
+			Healthy:   !now.Before(endpoint.downUntil),
+			LastError: endpoint.lastError,
+		})
+		endpoint.mu.Unlock()
+	}
+	return statuses
+}
+
+// CreateAccountContext creates the account on the primary, or on the first healthy endpoint when write failover is allowed
+func (client *FailoverClient) CreateAccountContext(ctx context.Context, account Account) (created Account, err error) {
+	err = client.call(ctx, true, func(config Configuration) (err error) {
+		created, err = config.CreateAccountContext(ctx, account)
+		return err
+	})
+	return created, err
+}
+
+// FetchAccountContext fetches the account from the first healthy endpoint
+func (client *FailoverClient) FetchAccountContext(ctx context.Context, accountID string) (fetched Account, err error) {
+	err = client.call(ctx, false, func(config Configuration) (err error) {
+		fetched, err = config.FetchAccountContext(ctx, accountID)
+		return err
+	})
+	return fetched, err
+}
+
+// ListAccountsContext lists a page of accounts from the first healthy endpoint
+func (client *FailoverClient) ListAccountsContext(ctx context.Context, pageNumber int, pageSize int, filter Filter) (accounts []Account, err error) {
+	err = client.call(ctx, false, func(config Configuration) (err error) {
+		accounts, err = config.ListAccountsContext(ctx, pageNumber, pageSize, filter)
+		return err
+	})
+	return accounts, err
+}
+
+// UpdateAccountContext updates the account on the primary, or on the first healthy endpoint when write failover is allowed
+func (client *FailoverClient) UpdateAccountContext(ctx context.Context, account Account) (updated Account, err error) {
+	err = client.call(ctx, true, func(config Configuration) (err error) {
+		updated, err = config.UpdateAccountContext(ctx, account)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteAccountContext deletes the account on the primary, or on the first healthy endpoint when write failover is allowed
+func (client *FailoverClient) DeleteAccountContext(ctx context.Context, accountID string, version int) error {
+	return client.call(ctx, true, func(config Configuration) error {
+		return config.DeleteAccountContext(ctx, accountID, version)
+	})
+}
+
+// call tries the endpoints in turn until one of them serves the call
+func (client *FailoverClient) call(ctx context.Context, write bool, do func(config Configuration) error) error {
+	route, _ := ctx.Value(routeKey{}).(*Route)
+
+	var err error
+	for _, endpoint := range client.candidates(write) {
+		err = do(endpoint.config)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !shouldFailOver(err) {
+			// The endpoint answered, even if with an error of the request itself
+			endpoint.markHealthy()
+			if route != nil {
+				route.Endpoint = endpoint.config.AccountAPIUrl
+			}
+			return err
+		}
+		endpoint.markFailed(err, client.now().Add(client.cooldown))
+		if route != nil {
+			route.Failed = append(route.Failed, endpoint.config.AccountAPIUrl)
+		}
+	}
+	return err
+}
+
+// candidates returns endpoints to try, healthy ones first in order of preference.
+// Writes only go to the primary unless write failover is allowed.
+func (client *FailoverClient) candidates(write bool) []*endpoint {
+	if write && !client.allowWriteFailover {
+		return client.endpoints[:1]
+	}
+	now := client.now()
+	var healthy, failed []*endpoint
+	for _, endpoint := range client.endpoints {
+		endpoint.mu.Lock()
+		down := now.Before(endpoint.downUntil)
+		endpoint.mu.Unlock()
+		if down {
+			failed = append(failed, endpoint)
+		} else {
+			healthy = append(healthy, endpoint)
+		}
+	}
+	// Failed endpoints are still tried as a last resort
+	return append(healthy, failed...)
+}
+
+func (endpoint *endpoint) markHealthy() {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.downUntil = time.Time{}
+	endpoint.lastError = nil
+}
+
+func (endpoint *endpoint) markFailed(err error, until time.Time) {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.downUntil = until
+	endpoint.lastError = err
+}
+
+// shouldFailOver tells whether err means the endpoint is unavailable rather than the request being wrong
+func shouldFailOver(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	// Errors of the HTTP client mean no response was received, e.g. a refused connection.
+	// Anything else, like an undecodable body, would fail the same way on every endpoint.
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
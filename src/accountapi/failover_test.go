@@ -0,0 +1,133 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRegionTestServer serves validUkAccount, or fails with given status when status is not zero
+func newRegionTestServer(status *int32, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if code := atomic.LoadInt32(status); code != 0 {
+			w.WriteHeader(int(code))
+			w.Write([]byte(`{"error_message":"region failure"}`))
+			return
+		}
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			if r.URL.Path == "/v1/organisation/accounts/" {
+				json.NewEncoder(w).Encode(Document[[]Account]{Data: []Account{validUkAccount}})
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(Document[Account]{Data: validUkAccount})
+	}))
+}
+
+func TestFailover(t *testing.T) {
+	var primaryStatus, secondaryStatus, primaryRequests, secondaryRequests int32
+	primary := newRegionTestServer(&primaryStatus, &primaryRequests)
+	defer primary.Close()
+	secondary := newRegionTestServer(&secondaryStatus, &secondaryRequests)
+	defer secondary.Close()
+
+	// Nothing listens on the closed server, so connecting to it is refused
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	primaryURL := primary.URL + "/v1/organisation/accounts/"
+	secondaryURL := secondary.URL + "/v1/organisation/accounts/"
+
+	client, err := NewFailoverClient(FailoverOptions{URLs: []string{primaryURL, secondaryURL}})
+	if err != nil {
+		t.Fatalf("Could not create failover client: %v", err)
+	}
+
+	t.Run("Test Reads Fail Over on 5xx", func(t *testing.T) {
+		atomic.StoreInt32(&primaryStatus, http.StatusServiceUnavailable)
+		var route Route
+		account, err := client.FetchAccountContext(WithRoute(context.Background(), &route), validUkAccount.ID)
+		if err != nil || account.ID != validUkAccount.ID {
+			t.Errorf("Fetch should be served by secondary: %v", err)
+		}
+		if route.Endpoint != secondaryURL || len(route.Failed) != 1 || route.Failed[0] != primaryURL {
+			t.Errorf("Route does not match: %+v", route)
+		}
+		if status := client.Status(); status[0].Healthy || status[0].LastError == nil || !status[1].Healthy {
+			t.Errorf("Primary should be reported unhealthy: %+v", status)
+		}
+
+		// Unhealthy primary is skipped until the cooldown passes
+		atomic.StoreInt32(&primaryRequests, 0)
+		route = Route{}
+		client.ListAccountsContext(WithRoute(context.Background(), &route), 0, 10, nil)
+		if atomic.LoadInt32(&primaryRequests) != 0 || route.Endpoint != secondaryURL {
+			t.Errorf("Unhealthy primary should not be tried first: %+v", route)
+		}
+
+		atomic.StoreInt32(&primaryStatus, 0)
+		client.now = func() time.Time { return time.Now().Add(DefaultCooldown) }
+		defer func() { client.now = time.Now }()
+		route = Route{}
+		client.FetchAccountContext(WithRoute(context.Background(), &route), validUkAccount.ID)
+		if route.Endpoint != primaryURL || !client.Status()[0].Healthy {
+			t.Errorf("Recovered primary should serve again: %+v", route)
+		}
+	})
+
+	t.Run("Test Client Errors Do Not Fail Over", func(t *testing.T) {
+		atomic.StoreInt32(&primaryStatus, http.StatusNotFound)
+		defer atomic.StoreInt32(&primaryStatus, 0)
+		atomic.StoreInt32(&secondaryRequests, 0)
+		_, err := client.FetchAccountContext(context.Background(), validUkAccount.ID)
+		if err == nil || atomic.LoadInt32(&secondaryRequests) != 0 {
+			t.Errorf("404 should be returned by primary, got: %v", err)
+		}
+	})
+
+	t.Run("Test Writes Pinned to Primary", func(t *testing.T) {
+		atomic.StoreInt32(&primaryStatus, http.StatusInternalServerError)
+		defer atomic.StoreInt32(&primaryStatus, 0)
+		atomic.StoreInt32(&secondaryRequests, 0)
+		if _, err := client.CreateAccountContext(context.Background(), validUkAccount); err == nil {
+			t.Errorf("Create should fail with the primary")
+		}
+		if err := client.DeleteAccountContext(context.Background(), validUkAccount.ID, 0); err == nil {
+			t.Errorf("Delete should fail with the primary")
+		}
+		if atomic.LoadInt32(&secondaryRequests) != 0 {
+			t.Errorf("Writes should not be sent to secondary")
+		}
+	})
+
+	t.Run("Test Allowed Write Failover", func(t *testing.T) {
+		client, _ := NewFailoverClient(FailoverOptions{
+			URLs:               []string{unreachable.URL + "/v1/organisation/accounts/", secondaryURL},
+			AllowWriteFailover: true,
+		})
+		var route Route
+		_, err := client.CreateAccountContext(WithRoute(context.Background(), &route), validUkAccount)
+		if err != nil || route.Endpoint != secondaryURL {
+			t.Errorf("Create should fail over on connection error: %+v %v", route, err)
+		}
+	})
+
+	if _, err := NewFailoverClient(FailoverOptions{URLs: []string{"localhost:8080"}}); err == nil {
+		t.Errorf("Invalid URL should be rejected")
+	}
+}
@@ -0,0 +1,177 @@
+package accountapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// HealthPath is the health endpoint of the API, resolved like the paths of NewResource
+const HealthPath = "/v1/health"
+
+// HealthReport describes whether the API could be reached and how long it took
+type HealthReport struct {
+	Healthy bool `json:"healthy"`
+	// Endpoint is the URL that was checked
+	Endpoint string `json:"endpoint"`
+	// Check is "health" when the health endpoint answered, or "list" when a single account page
+	// was listed because the API has no health endpoint
+	Check      string        `json:"check"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Timings    HealthTimings `json:"timings"`
+	CheckedAt  time.Time     `json:"checked_at"`
+}
+
+// HealthTimings are durations of the phases of the request. DNS, Connect and TLS are zero
+// when an idle connection was reused.
+type HealthTimings struct {
+	DNS        time.Duration
+	Connect    time.Duration
+	TLS        time.Duration
+	FirstByte  time.Duration
+	Total      time.Duration
+	ReusedConn bool
+	RemoteAddr string
+	// TLSVersion is the negotiated version, e.g. "TLS 1.3", or empty for plain HTTP
+	TLSVersion string
+}
+
+// MarshalJSON writes durations in human readable form, e.g. "1.2ms"
+func (timings HealthTimings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"dns":         timings.DNS.String(),
+		"connect":     timings.Connect.String(),
+		"tls":         timings.TLS.String(),
+		"first_byte":  timings.FirstByte.String(),
+		"total":       timings.Total.String(),
+		"reused_conn": timings.ReusedConn,
+		"remote_addr": timings.RemoteAddr,
+		"tls_version": timings.TLSVersion,
+	})
+}
+
+// Ping returns an error when the API can not be reached or is not healthy
+func (config Configuration) Ping(ctx context.Context) error {
+	report := config.HealthCheck(ctx)
+	if !report.Healthy {
+		return fmt.Errorf("Account API at %v is not healthy: %v", report.Endpoint, report.Error)
+	}
+	return nil
+}
+
+// HealthCheck calls the health endpoint of the API, falling back to listing a single account
+// when the API does not have one, and reports the outcome with timings of the request
+func (config Configuration) HealthCheck(ctx context.Context) HealthReport {
+	healthURL, err := config.apiURL(HealthPath)
+	if err != nil {
+		return HealthReport{Endpoint: config.AccountAPIUrl, Error: err.Error(), CheckedAt: time.Now()}
+	}
+
+	report := config.tracedGet(ctx, healthURL, func(body []byte) error {
+		var health struct {
+			Status string `json:"status"`
+		}
+		// The body is only checked when it tells the status
+		if json.Unmarshal(body, &health) == nil && health.Status != "" && health.Status != "up" {
+			return fmt.Errorf("status is %v", health.Status)
+		}
+		return nil
+	})
+	report.Check = "health"
+	if report.StatusCode != http.StatusNotFound {
+		return report
+	}
+
+	report = config.tracedGet(ctx, config.AccountAPIUrl+"?page[number]=0&page[size]=1", func(body []byte) error {
+		return json.Unmarshal(body, &Document[[]Account]{})
+	})
+	report.Check = "list"
+	return report
+}
+
+// tracedGet sends a GET request, measuring its phases, and checks a 200 response body with check
+func (config Configuration) tracedGet(ctx context.Context, endpoint string, check func(body []byte) error) HealthReport {
+	report := HealthReport{Endpoint: endpoint, CheckedAt: time.Now()}
+	timings := &report.Timings
+
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:      func(httptrace.DNSDoneInfo) { timings.DNS = time.Since(dnsStart) },
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				timings.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err == nil {
+				timings.TLS = time.Since(tlsStart)
+				timings.TLSVersion = tls.VersionName(state.Version)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			timings.ReusedConn = info.Reused
+			timings.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+		GotFirstResponseByte: func() { timings.FirstByte = time.Since(start) },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), GET, endpoint, nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	resp, err := config.httpClient().Do(req)
+	if err != nil {
+		timings.Total = time.Since(start)
+		report.Error = err.Error()
+		return report
+	}
+	defer resp.Body.Close()
+	maxBytes := config.maxResponseBytes()
+	body, err := io.ReadAll(&limitedBody{
+		ReadCloser: resp.Body,
+		remaining:  maxBytes,
+		tooLarge:   &ResponseTooLargeError{URL: endpoint, Limit: maxBytes},
+	})
+	timings.Total = time.Since(start)
+	report.StatusCode = resp.StatusCode
+
+	switch {
+	case err != nil:
+		report.Error = err.Error()
+	case resp.StatusCode != http.StatusOK:
+		report.Error = fmt.Sprintf("unexpected status code %v", resp.StatusCode)
+	default:
+		if err = check(body); err != nil {
+			report.Error = err.Error()
+		} else {
+			report.Healthy = true
+		}
+	}
+	return report
+}
+
+// HealthHandler answers with the JSON HealthReport, and status 200 when the API is healthy
+// or 503 when it is not, so it can be mounted as readiness probe of a service
+func (config Configuration) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := config.HealthCheck(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if report.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
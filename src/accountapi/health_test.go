@@ -0,0 +1,94 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	status := "up"
+	testRouter := http.NewServeMux()
+	testRouter.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"` + status + `"}`))
+	})
+	srv := httptest.NewServer(testRouter)
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+	t.Run("Test Health Endpoint", func(t *testing.T) {
+		report := testConfig.HealthCheck(context.Background())
+		if !report.Healthy || report.Check != "health" || report.Endpoint != srv.URL+"/v1/health" {
+			t.Errorf("Report does not match: %+v", report)
+		}
+		if report.Timings.Total <= 0 || report.Timings.FirstByte <= 0 || report.Timings.RemoteAddr == "" {
+			t.Errorf("Timings were not measured: %+v", report.Timings)
+		}
+		if err := testConfig.Ping(context.Background()); err != nil {
+			t.Errorf("Ping failed: %v", err)
+		}
+	})
+
+	t.Run("Test Unhealthy Status", func(t *testing.T) {
+		status = "down"
+		defer func() { status = "up" }()
+		if err := testConfig.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "down") {
+			t.Errorf("Ping should report status, got: %v", err)
+		}
+	})
+
+	t.Run("Test Large Body", func(t *testing.T) {
+		limitedConfig := testConfig
+		limitedConfig.MaxResponseBytes = 8
+		report := limitedConfig.HealthCheck(context.Background())
+		if report.Healthy || !strings.Contains(report.Error, "exceeds") {
+			t.Errorf("Body above MaxResponseBytes should fail the check: %+v", report)
+		}
+	})
+
+	t.Run("Test Fallback to List", func(t *testing.T) {
+		// An API without health endpoint is checked by listing a single account
+		srvNoHealth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == HealthPath {
+				http.NotFound(w, r)
+				return
+			}
+			if r.URL.Query().Get("page[size]") != "1" {
+				t.Errorf("Only a single account should be listed, got: %v", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"data":[]}`))
+		}))
+		defer srvNoHealth.Close()
+
+		fallbackConfig := Configuration{AccountAPIUrl: srvNoHealth.URL + "/v1/organisation/accounts/"}
+		report := fallbackConfig.HealthCheck(context.Background())
+		if !report.Healthy || report.Check != "list" {
+			t.Errorf("Report does not match: %+v", report)
+		}
+	})
+
+	t.Run("Test Handler", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		testConfig.HealthHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/ready", nil))
+		var report map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &report)
+		if recorder.Code != http.StatusOK || report["healthy"] != true {
+			t.Errorf("Healthy API should answer 200, got %v: %v", recorder.Code, recorder.Body.String())
+		}
+		if timings, ok := report["timings"].(map[string]interface{}); !ok || timings["total"] == "" {
+			t.Errorf("Timings should be reported: %v", report["timings"])
+		}
+
+		unreachable := Configuration{AccountAPIUrl: "http://127.0.0.1:1/v1/organisation/accounts/"}
+		recorder = httptest.NewRecorder()
+		unreachable.HealthHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/ready", nil))
+		if recorder.Code != http.StatusServiceUnavailable || !strings.Contains(recorder.Body.String(), `"error"`) {
+			t.Errorf("Unreachable API should answer 503, got %v: %v", recorder.Code, recorder.Body.String())
+		}
+	})
+}
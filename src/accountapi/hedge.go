@@ -0,0 +1,204 @@
+package accountapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of HedgeOptions
+const (
+	DefaultHedgePercentile   = 95
+	DefaultHedgeInitialDelay = 100 * time.Millisecond
+)
+
+// Latencies kept to compute the percentile, and observed before it is used instead of InitialDelay
+const (
+	hedgeWindow     = 1000
+	hedgeMinSamples = 20
+)
+
+// HedgeOptions configure a Hedger. Zero values select the defaults.
+type HedgeOptions struct {
+	// Delay after which a second request is sent. When zero, the delay follows the Percentile
+	// of latencies observed recently.
+	Delay time.Duration
+	// Percentile of observed latencies used as the delay, between 0 and 100, DefaultHedgePercentile by default
+	Percentile float64
+	// InitialDelay is used until enough latencies were observed, DefaultHedgeInitialDelay by default
+	InitialDelay time.Duration
+}
+
+// HedgeStats count requests of a Hedger
+type HedgeStats struct {
+	// Requests counts GET requests made
+	Requests int64
+	// Hedged counts requests for which a second request was sent
+	Hedged int64
+	// Won counts hedged requests whose response to the second request was used
+	Won int64
+	// Delay is the current delay before a second request is sent
+	Delay time.Duration
+}
+
+// Hedger sends a second, identical GET request when the first one is not answered within a
+// delay, and uses whichever response arrives first. The other request is cancelled. Set it as
+// Configuration.Hedger to hedge fetches and lists; creates, updates and deletes are never hedged.
+// A Hedger is safe for concurrent use and should be shared by all requests it measures.
+type Hedger struct {
+	options HedgeOptions
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+
+	requests int64
+	hedged   int64
+	won      int64
+}
+
+// NewHedger validates the options and returns a hedger using them
+func NewHedger(options HedgeOptions) (*Hedger, error) {
+	if options.Delay < 0 || options.InitialDelay < 0 {
+		return nil, fmt.Errorf("Hedge delays must not be negative")
+	}
+	if options.Percentile < 0 || options.Percentile > 100 {
+		return nil, fmt.Errorf("Hedge percentile must be between 0 and 100, got %v", options.Percentile)
+	}
+	if options.Percentile == 0 {
+		options.Percentile = DefaultHedgePercentile
+	}
+	if options.InitialDelay == 0 {
+		options.InitialDelay = DefaultHedgeInitialDelay
+	}
+	return &Hedger{options: options}, nil
+}
+
+// Stats returns counts of requests made so far
+func (hedger *Hedger) Stats() HedgeStats {
+	return HedgeStats{
+		Requests: atomic.LoadInt64(&hedger.requests),
+		Hedged:   atomic.LoadInt64(&hedger.hedged),
+		Won:      atomic.LoadInt64(&hedger.won),
+		Delay:    hedger.delay(),
+	}
+}
+
+// delay returns how long to wait for the first response before sending the second request
+func (hedger *Hedger) delay() time.Duration {
+	if hedger.options.Delay > 0 {
+		return hedger.options.Delay
+	}
+	hedger.mu.Lock()
+	if len(hedger.latencies) < hedgeMinSamples {
+		hedger.mu.Unlock()
+		return hedger.options.InitialDelay
+	}
+	sorted := append([]time.Duration(nil), hedger.latencies...)
+	hedger.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(float64(len(sorted)-1) * hedger.options.Percentile / 100)
+	return sorted[index]
+}
+
+// observe records the latency of a response, replacing the oldest one when the window is full
+func (hedger *Hedger) observe(latency time.Duration) {
+	hedger.mu.Lock()
+	defer hedger.mu.Unlock()
+	if len(hedger.latencies) < hedgeWindow {
+		hedger.latencies = append(hedger.latencies, latency)
+		return
+	}
+	hedger.latencies[hedger.next] = latency
+	hedger.next = (hedger.next + 1) % hedgeWindow
+}
+
+type hedgeResult struct {
+	body    io.ReadCloser
+	err     error
+	attempt int
+}
+
+// hedgedBody releases the context of the request that won when its body is closed
+type hedgedBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body hedgedBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+// do sends the request, and sends it again when it is not answered within the delay. A failed
+// response that another attempt may improve on, like a 5xx or a network error, is only used when
+// the other request fails as well. The other request is cancelled, and its body closed should
+// it arrive anyway.
+//
+// The time from sending the first request to the used response is observed as latency. When the
+// second request wins, it is less than the latency of the first, which is never known; the
+// percentile thus tells how long first requests take at least.
+func (hedger *Hedger) do(ctx context.Context, request func(ctx context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	atomic.AddInt64(&hedger.requests, 1)
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			body, err := request(attemptCtx)
+			results <- hedgeResult{body, err, attempt}
+		}()
+	}
+	start := time.Now()
+	send()
+	inFlight := 1
+	timer := time.NewTimer(hedger.delay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			atomic.AddInt64(&hedger.hedged, 1)
+			send()
+			inFlight++
+		case result := <-results:
+			inFlight--
+			if inFlight > 0 && Retryable(result.err) {
+				cancels[result.attempt]()
+				continue
+			}
+			for attempt, cancel := range cancels {
+				if attempt != result.attempt {
+					cancel()
+				}
+			}
+			if inFlight > 0 {
+				go func() {
+					if lost := <-results; lost.body != nil {
+						lost.body.Close()
+					}
+				}()
+			}
+
+			if !Retryable(result.err) && ctx.Err() == nil {
+				hedger.observe(time.Since(start))
+			}
+			if result.attempt > 0 {
+				atomic.AddInt64(&hedger.won, 1)
+			}
+			if result.err != nil {
+				cancels[result.attempt]()
+				return nil, result.err
+			}
+			return hedgedBody{result.body, cancels[result.attempt]}, nil
+		}
+	}
+}
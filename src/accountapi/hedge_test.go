@@ -0,0 +1,134 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHedgeTestServer serves validUkAccount. Requests are answered with the status returned by
+// respond for their number, counted from 1; a zero status is never answered until cancelled.
+func newHedgeTestServer(respond func(request int32) int, cancelled chan<- struct{}) *httptest.Server {
+	var requests int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := respond(atomic.AddInt32(&requests, 1))
+		if status == 0 {
+			<-r.Context().Done()
+			cancelled <- struct{}{}
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(`{"error_message":"failure"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(Document[Account]{Data: validUkAccount})
+	}))
+}
+
+func TestHedging(t *testing.T) {
+	slowFirst := func(request int32) int {
+		if request == 1 {
+			return 0
+		}
+		return http.StatusOK
+	}
+
+	t.Run("Test slow request is hedged", func(t *testing.T) {
+		cancelled := make(chan struct{}, 2)
+		server := newHedgeTestServer(slowFirst, cancelled)
+		defer server.Close()
+		hedger, _ := NewHedger(HedgeOptions{Delay: 10 * time.Millisecond})
+		config := Configuration{AccountAPIUrl: server.URL + "/v1/organisation/accounts/", Hedger: hedger}
+
+		account, err := config.FetchAccountContext(context.Background(), validUkAccount.ID)
+		if err != nil || account.ID != validUkAccount.ID {
+			t.Fatalf("Hedged request should be answered: %v", err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Errorf("Slow request should be cancelled")
+		}
+		if stats := hedger.Stats(); stats.Requests != 1 || stats.Hedged != 1 || stats.Won != 1 || stats.Delay != 10*time.Millisecond {
+			t.Errorf("Stats do not match: %+v", stats)
+		}
+	})
+
+	t.Run("Test fast request is not hedged", func(t *testing.T) {
+		server := newHedgeTestServer(func(int32) int { return http.StatusOK }, nil)
+		defer server.Close()
+		hedger, _ := NewHedger(HedgeOptions{Delay: time.Minute})
+		config := Configuration{AccountAPIUrl: server.URL + "/v1/organisation/accounts/", Hedger: hedger}
+
+		for i := 0; i < 2; i++ {
+			if _, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+		}
+		if stats := hedger.Stats(); stats.Requests != 2 || stats.Hedged != 0 {
+			t.Errorf("Stats do not match: %+v", stats)
+		}
+	})
+
+	t.Run("Test server error waits for the hedged request", func(t *testing.T) {
+		server := newHedgeTestServer(func(request int32) int {
+			if request == 1 {
+				// Fails after the hedged request was sent, but before it is answered
+				time.Sleep(50 * time.Millisecond)
+				return http.StatusBadGateway
+			}
+			time.Sleep(100 * time.Millisecond)
+			return http.StatusOK
+		}, nil)
+		defer server.Close()
+		hedger, _ := NewHedger(HedgeOptions{Delay: 10 * time.Millisecond})
+		config := Configuration{AccountAPIUrl: server.URL + "/v1/organisation/accounts/", Hedger: hedger}
+
+		if _, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err != nil {
+			t.Errorf("Hedged request should be used instead of the failed one: %v", err)
+		}
+	})
+
+	t.Run("Test other errors are used", func(t *testing.T) {
+		server := newHedgeTestServer(func(int32) int { return http.StatusNotFound }, nil)
+		defer server.Close()
+		hedger, _ := NewHedger(HedgeOptions{Delay: time.Minute})
+		config := Configuration{AccountAPIUrl: server.URL + "/v1/organisation/accounts/", Hedger: hedger}
+
+		if _, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err == nil || !err.(*APIError).IsNotFound() {
+			t.Errorf("Not found error should be returned: %v", err)
+		}
+	})
+}
+
+func TestHedgeDelay(t *testing.T) {
+	hedger, err := NewHedger(HedgeOptions{Percentile: 90})
+	if err != nil {
+		t.Fatalf("Could not create hedger: %v", err)
+	}
+	for i := 1; i < hedgeMinSamples; i++ {
+		hedger.observe(time.Millisecond)
+	}
+	if delay := hedger.Stats().Delay; delay != DefaultHedgeInitialDelay {
+		t.Errorf("Initial delay should be used until enough latencies are observed: %v", delay)
+	}
+
+	for i := 1; i <= hedgeWindow+100; i++ {
+		hedger.observe(time.Duration(i%100+1) * time.Millisecond)
+	}
+	if delay := hedger.Stats().Delay; delay < 89*time.Millisecond || delay > 91*time.Millisecond {
+		t.Errorf("Delay should follow the percentile of latencies: %v", delay)
+	}
+
+	for _, options := range []HedgeOptions{{Percentile: 101}, {Delay: -time.Second}} {
+		if _, err := NewHedger(options); err == nil {
+			t.Errorf("Options should be invalid: %+v", options)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package accountapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Document is a JSON:API top-level document, see https://jsonapi.org/format/#document-top-level.
+// T is the type of primary data, e.g. Account for a single resource or []Account for a collection.
+type Document[T any] struct {
+	Data     T                `json:"data"`
+	Errors   []ErrorObject    `json:"errors,omitempty"`
+	Links    Links            `json:"links,omitempty"`
+	Meta     Meta             `json:"meta,omitempty"`
+	Included []ResourceObject `json:"included,omitempty"`
+}
+
+// ResourceObject is a resource of any type, with attributes left undecoded.
+// It is used for included resources, which may be of different types.
+type ResourceObject struct {
+	Type          string          `json:"type"`
+	ID            string          `json:"id"`
+	Attributes    json.RawMessage `json:"attributes,omitempty"`
+	Relationships Relationships   `json:"relationships,omitempty"`
+	Links         Links           `json:"links,omitempty"`
+	Meta          Meta            `json:"meta,omitempty"`
+}
+
+// Decode unmarshals attributes of the resource into target
+func (resource ResourceObject) Decode(target interface{}) error {
+	if len(resource.Attributes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resource.Attributes, target)
+}
+
+// Relationships of a resource by name, e.g. "master_account"
+type Relationships map[string]Relationship
+
+// Relationship links a resource to others. Data is a single ResourceIdentifier,
+// an array of them or null, so it is left undecoded.
+type Relationship struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Links Links           `json:"links,omitempty"`
+	Meta  Meta            `json:"meta,omitempty"`
+}
+
+// ResourceIdentifier identifies a related resource
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Identifiers decodes Data of the relationship, whether it is a single identifier or an array
+func (relationship Relationship) Identifiers() ([]ResourceIdentifier, error) {
+	var identifiers []ResourceIdentifier
+	if len(relationship.Data) == 0 || string(relationship.Data) == "null" {
+		return identifiers, nil
+	}
+	if relationship.Data[0] == '[' {
+		err := json.Unmarshal(relationship.Data, &identifiers)
+		return identifiers, err
+	}
+	var identifier ResourceIdentifier
+	err := json.Unmarshal(relationship.Data, &identifier)
+	return append(identifiers, identifier), err
+}
+
+// Links by name, e.g. "self" or "next". Link objects are reduced to their href.
+type Links map[string]string
+
+// UnmarshalJSON accepts links given as strings, as {"href": ...} objects or as null
+func (links *Links) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*links = Links{}
+	for name, value := range raw {
+		var href string
+		switch {
+		case string(value) == "null":
+			continue
+		case len(value) > 0 && value[0] == '{':
+			var object struct {
+				Href string `json:"href"`
+			}
+			if err := json.Unmarshal(value, &object); err != nil {
+				return err
+			}
+			href = object.Href
+		default:
+			if err := json.Unmarshal(value, &href); err != nil {
+				return fmt.Errorf("link %q: %v", name, err)
+			}
+		}
+		(*links)[name] = href
+	}
+	return nil
+}
+
+// Meta holds non-standard information, e.g. a total count of listed resources
+type Meta map[string]interface{}
+
+// ErrorObject describes a single problem, see https://jsonapi.org/format/#error-objects
+type ErrorObject struct {
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+	Meta   Meta         `json:"meta,omitempty"`
+}
+
+// ErrorSource points at the part of the request that caused the error
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+func (object ErrorObject) String() string {
+	switch {
+	case object.Title != "" && object.Detail != "":
+		return object.Title + ": " + object.Detail
+	case object.Detail != "":
+		return object.Detail
+	default:
+		return object.Title
+	}
+}
+
+// decodeDocument decodes a response body into a document while it is read
+func decodeDocument[T any](body io.Reader) (Document[T], error) {
+	var document Document[T]
+	err := json.NewDecoder(body).Decode(&document)
+	return document, err
+}
+
+// pageStream decodes a listed page while its response body is read, one resource at a time.
+// Members of the document other than data are kept in document once the whole body was read.
+type pageStream[T any] struct {
+	body     io.ReadCloser
+	decoder  *json.Decoder
+	document Document[[]T]
+	members  map[string]json.RawMessage
+	started  bool
+	inData   bool
+	done     bool
+}
+
+func newPageStream[T any](body io.ReadCloser) *pageStream[T] {
+	return &pageStream[T]{body: body, decoder: json.NewDecoder(body), members: map[string]json.RawMessage{}}
+}
+
+// next decodes the next resource of data. It returns false when all resources were read.
+func (stream *pageStream[T]) next() (T, bool, error) {
+	var resource T
+	if stream.done {
+		return resource, false, nil
+	}
+	if !stream.started {
+		if err := stream.expect(json.Delim('{')); err != nil {
+			return resource, false, err
+		}
+		stream.started = true
+	}
+	for {
+		if stream.inData {
+			if stream.decoder.More() {
+				err := stream.decoder.Decode(&resource)
+				return resource, err == nil, err
+			}
+			if err := stream.expect(json.Delim(']')); err != nil {
+				return resource, false, err
+			}
+			stream.inData = false
+		}
+		if !stream.decoder.More() {
+			if err := stream.expect(json.Delim('}')); err != nil {
+				return resource, false, err
+			}
+			stream.done = true
+			// Reading the rest of the body lets the connection be reused
+			if _, err := io.Copy(ioutil.Discard, stream.body); err != nil {
+				return resource, false, err
+			}
+			bMembers, _ := json.Marshal(stream.members)
+			return resource, false, json.Unmarshal(bMembers, &stream.document)
+		}
+
+		token, err := stream.decoder.Token()
+		if err != nil {
+			return resource, false, err
+		}
+		if token != "data" {
+			var value json.RawMessage
+			if err := stream.decoder.Decode(&value); err != nil {
+				return resource, false, err
+			}
+			stream.members[token.(string)] = value
+			continue
+		}
+		// Data of a page is an array, or null when the API has no resources to list
+		if token, err = stream.decoder.Token(); err != nil {
+			return resource, false, err
+		}
+		switch token {
+		case json.Delim('['):
+			stream.inData = true
+		case nil:
+		default:
+			return resource, false, fmt.Errorf("Invalid list response: data is not an array")
+		}
+	}
+}
+
+// expect reads the next token and fails unless it is given delimiter
+func (stream *pageStream[T]) expect(delim json.Delim) error {
+	token, err := stream.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("Invalid list response: expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// close releases the response body
+func (stream *pageStream[T]) close() error {
+	return stream.body.Close()
+}
@@ -0,0 +1,99 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testListDocument = `{
+  "data": [{
+    "type": "accounts",
+    "id": "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc",
+    "organisation_id": "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c",
+    "attributes": {"country": "GB"},
+    "relationships": {
+      "master_account": {"data": [{"type": "accounts", "id": "a52d13a4-f435-4c00-cfad-f5e7ac5972df"}]},
+      "account_events": {"data": null, "links": {"related": {"href": "/v1/organisation/accounts/ad27e265-9605-4b4b-a0e5-3003ea9cc4dc/events"}}}
+    }
+  }],
+  "included": [{"type": "account_events", "id": "1", "attributes": {"event_type": "created"}}],
+  "links": {"self": "/v1/organisation/accounts?page[number]=0", "next": {"href": "/v1/organisation/accounts?page[number]=1"}, "prev": null},
+  "meta": {"total": 21}
+}`
+
+func TestDocument(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if r.URL.Path != "/v1/organisation/accounts/" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"errors":[{"status":"422","title":"Invalid attribute","detail":"country is required","source":{"pointer":"/data/attributes/country"}}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testListDocument))
+	}))
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+	t.Run("Test Links and Meta", func(t *testing.T) {
+		document, err := testConfig.ListAccountsDocument(context.Background(), 0, 1, nil)
+		if err != nil {
+			t.Fatalf("Could not list accounts: %v", err)
+		}
+		if len(document.Data) != 1 || document.Data[0].Attributes.Country != "GB" {
+			t.Errorf("Data does not match: %+v", document.Data)
+		}
+		if document.Links["next"] != "/v1/organisation/accounts?page[number]=1" || document.Links["self"] == "" {
+			t.Errorf("Links do not match: %v", document.Links)
+		}
+		if _, ok := document.Links["prev"]; ok {
+			t.Errorf("Null link should be left out: %v", document.Links)
+		}
+		if document.Meta["total"] != float64(21) {
+			t.Errorf("Meta does not match: %v", document.Meta)
+		}
+	})
+
+	t.Run("Test Relationships and Included", func(t *testing.T) {
+		document, _ := decodeDocument[[]Account](strings.NewReader(testListDocument))
+		master, err := document.Data[0].Relationships["master_account"].Identifiers()
+		if err != nil || len(master) != 1 || master[0].ID != "a52d13a4-f435-4c00-cfad-f5e7ac5972df" {
+			t.Errorf("Relationship does not match: %v %v", master, err)
+		}
+		events := document.Data[0].Relationships["account_events"]
+		if identifiers, _ := events.Identifiers(); len(identifiers) != 0 || events.Links["related"] == "" {
+			t.Errorf("Empty relationship does not match: %+v", events)
+		}
+
+		var attributes struct {
+			EventType string `json:"event_type"`
+		}
+		if len(document.Included) != 1 || document.Included[0].Decode(&attributes) != nil || attributes.EventType != "created" {
+			t.Errorf("Included resource does not match: %+v", document.Included)
+		}
+	})
+
+	t.Run("Test Errors", func(t *testing.T) {
+		_, err := testConfig.FetchAccount(validUkAccount.ID)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || len(apiErr.Errors) != 1 || apiErr.Errors[0].Source.Pointer != "/data/attributes/country" {
+			t.Fatalf("Error objects should be returned: %#v", err)
+		}
+		if err.Error() != "Invalid attribute: country is required" {
+			t.Errorf("Error message does not match: %v", err)
+		}
+	})
+
+	t.Run("Test Encoding", func(t *testing.T) {
+		bDocument, _ := json.Marshal(Document[Account]{Data: Account{Type: "accounts", ID: "1"}})
+		if string(bDocument) != `{"data":{"type":"accounts","id":"1","organisation_id":"","attributes":{"country":""}}}` {
+			t.Errorf("Empty members should be left out: %s", bDocument)
+		}
+	})
+}
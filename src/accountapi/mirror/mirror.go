@@ -0,0 +1,270 @@
+// Package mirror keeps a local copy of all accounts in an embedded SQL database, so that
+// tools can query accounts without calling the API for each of them.
+//
+// The mirror uses the SQLite dialect through database/sql. The caller opens the database
+// with a SQLite driver of their choice, e.g. modernc.org/sqlite:
+//
+//	db, err := sql.Open("sqlite", "accounts.db")
+//	...
+//	accounts, err := mirror.Open(ctx, db, config)
+//	...
+//	result, err := accounts.Sync(ctx)
+//	found, err := accounts.ByIBAN(ctx, "GB11NWBK40030041426819")
+package mirror
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Mirror holds accounts listed from the API in the accounts table. Accounts deleted from the
+// API are kept as tombstones, with the time their deletion was noticed in deleted_on.
+type Mirror struct {
+	db     *sql.DB
+	config accountapi.Configuration
+	// PageSize of listed pages, 100 when zero
+	PageSize int
+	// now returns the time syncs and deletions are recorded at
+	now func() time.Time
+}
+
+// SyncResult counts changes a sync made to the mirror
+type SyncResult struct {
+	Created   int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+// Tombstone is an account deleted from the API, as it was last seen
+type Tombstone struct {
+	Account   accountapi.Account
+	DeletedOn time.Time
+}
+
+// Open creates the tables of the mirror in db unless they exist, and returns the mirror
+// of accounts listed with given configuration
+func Open(ctx context.Context, db *sql.DB, config accountapi.Configuration) (*Mirror, error) {
+	for _, statement := range schema() {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return nil, fmt.Errorf("Could not create mirror schema: %v", err)
+		}
+	}
+	if err := addMissingColumns(ctx, db); err != nil {
+		return nil, fmt.Errorf("Could not update mirror schema: %v", err)
+	}
+	return &Mirror{db: db, config: config, now: time.Now}, nil
+}
+
+// addMissingColumns adds columns of attributes that were added to Account after the table was created.
+// Their values are filled in when the accounts change, or by syncing a new mirror.
+func addMissingColumns(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info('accounts')")
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if !existing[column.name] {
+			if _, err := db.ExecContext(ctx, "ALTER TABLE accounts ADD COLUMN "+column.name+" "+column.sqlType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sync lists all accounts and stores those that are new or changed since the last sync,
+// judged by their Version and ModifiedOn. Stored accounts that are no longer listed become
+// tombstones. Each sync is a full rescan: the API can not list accounts modified since a time,
+// and deletions are only noticed by accounts missing from the listing. Changes are written
+// while accounts are listed, in one transaction that is committed when the listing completed,
+// so a failed sync leaves the mirror as it was.
+func (mirror *Mirror) Sync(ctx context.Context) (SyncResult, error) {
+	var result SyncResult
+	stored, err := mirror.storedVersions(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := mirror.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	now := mirror.now().UTC().Format(accountapi.TimestampFormat)
+	upsert := upsertStatement()
+	listed := map[string]bool{}
+	it := mirror.config.IterateAccounts(ctx, mirror.PageSize, nil)
+	for it.Next() {
+		account := it.Account()
+		listed[account.ID] = true
+		previous, exists := stored[account.ID]
+		switch {
+		case !exists:
+			result.Created++
+		case previous.Version == account.Version && previous.ModifiedOn.Equal(account.ModifiedOn):
+			result.Unchanged++
+			continue
+		default:
+			result.Updated++
+		}
+		args, err := upsertArgs(account, now)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, upsert, args...)
+		}
+		if err != nil {
+			return SyncResult{}, fmt.Errorf("Could not store account %v: %v", account.ID, err)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return SyncResult{}, err
+	}
+
+	for id := range stored {
+		if listed[id] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET deleted_on = ?, synced_on = ? WHERE id = ?", now, now, id); err != nil {
+			return SyncResult{}, fmt.Errorf("Could not store deletion of account %v: %v", id, err)
+		}
+		result.Deleted++
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO sync_state (name, value) VALUES ('last_sync', ?) ON CONFLICT (name) DO UPDATE SET value = excluded.value", now)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	return result, tx.Commit()
+}
+
+// storedVersions returns version and modification time of stored accounts that are not deleted
+func (mirror *Mirror) storedVersions(ctx context.Context) (map[string]accountapi.Account, error) {
+	rows, err := mirror.db.QueryContext(ctx, "SELECT id, version, modified_on FROM accounts WHERE deleted_on IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[string]accountapi.Account{}
+	for rows.Next() {
+		var account accountapi.Account
+		var version sql.NullInt64
+		var modifiedOn sql.NullString
+		if err := rows.Scan(&account.ID, &version, &modifiedOn); err != nil {
+			return nil, err
+		}
+		account.Version = int(version.Int64)
+		if account.ModifiedOn, err = accountapi.ParseTimestamp(modifiedOn.String); err != nil {
+			return nil, err
+		}
+		stored[account.ID] = account
+	}
+	return stored, rows.Err()
+}
+
+// LastSync returns when the mirror was last synced, the zero time if never
+func (mirror *Mirror) LastSync(ctx context.Context) (time.Time, error) {
+	var value string
+	err := mirror.db.QueryRowContext(ctx, "SELECT value FROM sync_state WHERE name = 'last_sync'").Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(accountapi.TimestampFormat, value)
+}
+
+// Get returns the stored account with given ID. Deleted accounts are not found.
+func (mirror *Mirror) Get(ctx context.Context, accountID string) (accountapi.Account, bool, error) {
+	accounts, err := mirror.query(ctx, "id = ?", accountID)
+	if err != nil || len(accounts) == 0 {
+		return accountapi.Account{}, false, err
+	}
+	return accounts[0], true, nil
+}
+
+// ByIBAN returns stored accounts with given IBAN
+func (mirror *Mirror) ByIBAN(ctx context.Context, iban string) ([]accountapi.Account, error) {
+	return mirror.query(ctx, "iban = ?", strings.ReplaceAll(iban, " ", ""))
+}
+
+// ByBIC returns stored accounts with given BIC
+func (mirror *Mirror) ByBIC(ctx context.Context, bic string) ([]accountapi.Account, error) {
+	return mirror.query(ctx, "bic = ?", bic)
+}
+
+// ByOrganisation returns stored accounts of given organisation
+func (mirror *Mirror) ByOrganisation(ctx context.Context, organisationID string) ([]accountapi.Account, error) {
+	return mirror.query(ctx, "organisation_id = ?", organisationID)
+}
+
+// query returns accounts that are not deleted and match the condition, ordered by ID
+func (mirror *Mirror) query(ctx context.Context, condition string, args ...interface{}) ([]accountapi.Account, error) {
+	rows, err := mirror.db.QueryContext(ctx, "SELECT document FROM accounts WHERE deleted_on IS NULL AND "+condition+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []accountapi.Account
+	for rows.Next() {
+		var document string
+		var account accountapi.Account
+		if err := rows.Scan(&document); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(document), &account); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// Tombstones returns accounts deleted from the API since given time, in order of deletion
+func (mirror *Mirror) Tombstones(ctx context.Context, since time.Time) ([]Tombstone, error) {
+	rows, err := mirror.db.QueryContext(ctx, "SELECT document, deleted_on FROM accounts WHERE deleted_on >= ? ORDER BY deleted_on, id",
+		since.UTC().Format(accountapi.TimestampFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []Tombstone
+	for rows.Next() {
+		var document, deletedOn string
+		var tombstone Tombstone
+		if err := rows.Scan(&document, &deletedOn); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(document), &tombstone.Account); err != nil {
+			return nil, err
+		}
+		if tombstone.DeletedOn, err = time.Parse(accountapi.TimestampFormat, deletedOn); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones, rows.Err()
+}
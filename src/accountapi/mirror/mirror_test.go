@@ -0,0 +1,118 @@
+package mirror
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+	"github.com/dexpetkovic/zero-one-go/src/internal/sqltest"
+)
+
+const (
+	organisationID = "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	ukAccountID    = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	nlAccountID    = "bf33e333-9605-4b4b-a0e5-3003ea9cc4dc"
+)
+
+func newAccount(id string, country string, bic string, iban string) accountapi.Account {
+	account := accountapi.Account{Type: "accounts", ID: id, OrganisationID: organisationID}
+	account.Attributes.Country = country
+	account.Attributes.Bic = bic
+	account.Attributes.Iban = iban
+	return account
+}
+
+func TestSchema(t *testing.T) {
+	statements := strings.Join(schema(), "\n")
+	for _, definition := range []string{"id TEXT PRIMARY KEY", "organisation_id TEXT", "version INTEGER", "modified_on TEXT", "iban TEXT", "name TEXT", "joint_account INTEGER", "deleted_on TEXT"} {
+		if !strings.Contains(statements, "\t"+definition+",") {
+			t.Errorf("Schema is missing %q:\n%v", definition, statements)
+		}
+	}
+	if strings.Contains(statements, "relationships") {
+		t.Errorf("Relationships should only be kept in the document")
+	}
+
+	account := newAccount(ukAccountID, "GB", "NWBKGB22", "GB11NWBK40030041426819")
+	account.Attributes.Name = []string{"Jane Doe"}
+	account.Attributes.JointAccount = true
+	account.ModifiedOn, _ = accountapi.ParseTimestamp("2021-01-09T14:24:54.5+01:00")
+	args, err := upsertArgs(account, "2026-10-19T12:00:00.000Z")
+	if err != nil || len(args) != strings.Count(upsertStatement(), "?") {
+		t.Fatalf("Arguments do not match the statement: %v %v", args, err)
+	}
+	values := map[string]interface{}{}
+	for i, column := range columns {
+		values[column.name] = args[i]
+	}
+	if values["name"] != `["Jane Doe"]` || values["joint_account"] != 1 || values["modified_on"] != "2021-01-09T13:24:54.500Z" || values["created_on"] != nil {
+		t.Errorf("Stored values do not match: %v", values)
+	}
+}
+
+func TestSync(t *testing.T) {
+	db := sqltest.Open(t)
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	ctx := context.Background()
+
+	uk := newAccount(ukAccountID, "GB", "NWBKGB22", "GB11NWBK40030041426819")
+	nl := newAccount(nlAccountID, "NL", "NLABNA01", "")
+	config.CreateAccount(uk)
+	config.CreateAccount(nl)
+
+	mirror, err := Open(ctx, db, config)
+	if err != nil {
+		t.Fatalf("Could not open mirror: %v", err)
+	}
+	start := time.Now()
+	if result, err := mirror.Sync(ctx); err != nil || result != (SyncResult{Created: 2}) {
+		t.Fatalf("First sync should store all accounts: %+v %v", result, err)
+	}
+
+	update := accountapi.Account{Type: "accounts", ID: ukAccountID}
+	update.Attributes.Bic = "NWBKGB42"
+	config.UpdateAccount(update)
+	config.DeleteAccount(nlAccountID, 0)
+
+	if result, err := mirror.Sync(ctx); err != nil || result != (SyncResult{Updated: 1, Deleted: 1}) {
+		t.Fatalf("Sync should store changes only: %+v %v", result, err)
+	}
+	if result, _ := mirror.Sync(ctx); result != (SyncResult{Unchanged: 1}) {
+		t.Errorf("Sync without changes should store nothing: %+v", result)
+	}
+
+	if found, err := mirror.ByIBAN(ctx, "GB11 NWBK 4003 0041 4268 19"); err != nil || len(found) != 1 || found[0].Attributes.Bic != "NWBKGB42" || found[0].Version != 1 {
+		t.Errorf("Account by IBAN does not match: %+v %v", found, err)
+	}
+	if found, _ := mirror.ByBIC(ctx, "NWBKGB22"); len(found) != 0 {
+		t.Errorf("Old BIC should not be found: %+v", found)
+	}
+	if found, _ := mirror.ByOrganisation(ctx, organisationID); len(found) != 1 || found[0].ID != ukAccountID {
+		t.Errorf("Deleted accounts should not be found: %+v", found)
+	}
+	if _, exists, _ := mirror.Get(ctx, nlAccountID); exists {
+		t.Errorf("Deleted account should not be found")
+	}
+
+	tombstones, err := mirror.Tombstones(ctx, start)
+	if err != nil || len(tombstones) != 1 || tombstones[0].Account.ID != nlAccountID || tombstones[0].DeletedOn.IsZero() {
+		t.Errorf("Deleted account should be a tombstone: %+v %v", tombstones, err)
+	}
+	if lastSync, err := mirror.LastSync(ctx); err != nil || lastSync.Before(start.Truncate(time.Millisecond)) {
+		t.Errorf("Last sync does not match: %v %v", lastSync, err)
+	}
+
+	// An account created again with the ID of a deleted one is revived
+	config.CreateAccount(nl)
+	if result, _ := mirror.Sync(ctx); result.Created != 1 {
+		t.Errorf("Recreated account should be stored: %+v", result)
+	}
+	if _, exists, _ := mirror.Get(ctx, nlAccountID); !exists {
+		t.Errorf("Recreated account should be found")
+	}
+}
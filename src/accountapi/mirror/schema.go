@@ -0,0 +1,151 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// column is a column of the accounts table, holding one field of Account
+type column struct {
+	name    string
+	sqlType string
+	// value returns the field of given account as stored
+	value func(account accountapi.Account) interface{}
+}
+
+// columns of the accounts table, derived from the JSON fields of Account and its attributes,
+// so that attributes added to Account become columns. Nested attributes are stored by their
+// name, lists as JSON and timestamps in UTC with TimestampFormat, which sorts chronologically.
+var columns = deriveColumns()
+
+// Columns managed by the mirror besides account fields
+const (
+	documentColumn  = "document"
+	deletedOnColumn = "deleted_on"
+	syncedOnColumn  = "synced_on"
+)
+
+var timestampType = reflect.TypeOf(accountapi.Timestamp{})
+
+func deriveColumns() []column {
+	var derived []column
+	var walk func(structType reflect.Type, index []int)
+	walk = func(structType reflect.Type, index []int) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fieldIndex := append(index[:len(index):len(index)], i)
+			if field.Type.Kind() == reflect.Struct && field.Type != timestampType {
+				walk(field.Type, fieldIndex)
+				continue
+			}
+			if sqlType := sqlTypeOf(field.Type); sqlType != "" {
+				derived = append(derived, column{name, sqlType, fieldValue(field.Type, fieldIndex)})
+			}
+		}
+	}
+	walk(reflect.TypeOf(accountapi.Account{}), nil)
+	return derived
+}
+
+// sqlTypeOf returns the SQLite type storing given field type, empty for fields that are only kept in the document
+func sqlTypeOf(fieldType reflect.Type) string {
+	switch {
+	case fieldType == timestampType, fieldType.Kind() == reflect.String:
+		return "TEXT"
+	case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.String:
+		return "TEXT"
+	case fieldType.Kind() == reflect.Bool, fieldType.Kind() == reflect.Int:
+		return "INTEGER"
+	}
+	return ""
+}
+
+func fieldValue(fieldType reflect.Type, index []int) func(account accountapi.Account) interface{} {
+	return func(account accountapi.Account) interface{} {
+		value := reflect.ValueOf(account).FieldByIndex(index)
+		switch {
+		case fieldType == timestampType:
+			timestamp := value.Interface().(accountapi.Timestamp)
+			if timestamp.IsZero() {
+				return nil
+			}
+			return timestamp.UTC().Format(accountapi.TimestampFormat)
+		case fieldType.Kind() == reflect.Slice:
+			if value.Len() == 0 {
+				return nil
+			}
+			bValue, _ := json.Marshal(value.Interface())
+			return string(bValue)
+		case fieldType.Kind() == reflect.Bool:
+			if value.Bool() {
+				return 1
+			}
+			return 0
+		}
+		return value.Interface()
+	}
+}
+
+// schema returns statements creating the tables and indexes of the mirror
+func schema() []string {
+	definitions := make([]string, 0, len(columns)+3)
+	for _, column := range columns {
+		definition := column.name + " " + column.sqlType
+		if column.name == "id" {
+			definition += " PRIMARY KEY"
+		}
+		definitions = append(definitions, definition)
+	}
+	definitions = append(definitions,
+		documentColumn+" TEXT NOT NULL",
+		deletedOnColumn+" TEXT",
+		syncedOnColumn+" TEXT NOT NULL")
+
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS accounts (\n\t%v\n)", strings.Join(definitions, ",\n\t")),
+		"CREATE INDEX IF NOT EXISTS accounts_iban ON accounts (iban)",
+		"CREATE INDEX IF NOT EXISTS accounts_bic ON accounts (bic)",
+		"CREATE INDEX IF NOT EXISTS accounts_organisation_id ON accounts (organisation_id)",
+		"CREATE TABLE IF NOT EXISTS sync_state (name TEXT PRIMARY KEY, value TEXT NOT NULL)",
+	}
+}
+
+// upsertStatement inserts an account, or replaces the stored one, reviving it when it was deleted
+func upsertStatement() string {
+	names := make([]string, 0, len(columns)+3)
+	updates := make([]string, 0, len(columns)+3)
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	names = append(names, documentColumn, deletedOnColumn, syncedOnColumn)
+	for _, name := range names {
+		if name != "id" {
+			updates = append(updates, name+" = excluded."+name)
+		}
+	}
+	return fmt.Sprintf("INSERT INTO accounts (%v) VALUES (%v) ON CONFLICT (id) DO UPDATE SET %v",
+		strings.Join(names, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "),
+		strings.Join(updates, ", "))
+}
+
+// upsertArgs returns values of the upsert statement for given account
+func upsertArgs(account accountapi.Account, syncedOn string) ([]interface{}, error) {
+	bDocument, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(columns)+3)
+	for _, column := range columns {
+		args = append(args, column.value(account))
+	}
+	return append(args, string(bDocument), nil, syncedOn), nil
+}
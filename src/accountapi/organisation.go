@@ -0,0 +1,28 @@
+package accountapi
+
+// OrganisationsPath is where organisation resources are served
+const OrganisationsPath = "/v1/organisation/units/"
+
+// Organisation is an organisational unit of the Bank, owning accounts
+type Organisation struct {
+	Type           string                 `json:"type"`
+	ID             string                 `json:"id"`
+	OrganisationID string                 `json:"organisation_id,omitempty"`
+	Version        int                    `json:"version,omitempty"`
+	CreatedOn      Timestamp              `json:"created_on,omitzero"`
+	ModifiedOn     Timestamp              `json:"modified_on,omitzero"`
+	Attributes     OrganisationAttributes `json:"attributes"`
+}
+
+// OrganisationAttributes describe an organisation. Optional attributes can be omitted.
+type OrganisationAttributes struct {
+	Name                 string `json:"name"`
+	ParentOrganisationID string `json:"parent_organisation_id,omitempty"`
+	Country              string `json:"country,omitempty"`
+	Status               string `json:"status,omitempty"`
+}
+
+// Organisations returns the client of organisation resources
+func (config Configuration) Organisations() Resource[Organisation] {
+	return NewResource[Organisation](config, OrganisationsPath)
+}
@@ -0,0 +1,370 @@
+// Package outbox keeps account commands that must not be lost. Commands are persisted to a
+// Store before they are sent, processed in the background with the retry policy of the outbox,
+// and kept as dead letters when they fail for good, until they are replayed or discarded.
+//
+//	store, err := outbox.NewFileStore("/var/lib/myservice/outbox")
+//	...
+//	box := outbox.New(store, config, accountapi.RetryPolicy{MaxAttempts: 10})
+//	go box.Run(ctx)
+//	command, err := box.Create(ctx, account)
+//
+// A command that was saved is processed even when the service stops before sending it, by
+// the next outbox running over the same store.
+//
+// Only one outbox may process a store at a time, by calling Run or Process; the outbox does not
+// lock the store against other processes. Two processing outboxes would both send a command,
+// and the second update of an account would fail with a version conflict. Other processes may
+// save commands to the store, e.g. with SQLStore.SaveTx or an Outbox they only call Enqueue of.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// CommandType tells what a command does to an account
+type CommandType string
+
+// Command types
+const (
+	Create CommandType = "create"
+	Update CommandType = "update"
+	Delete CommandType = "delete"
+)
+
+// DefaultPollInterval is how often Run looks for commands saved by other processes, e.g. with SQLStore.SaveTx
+const DefaultPollInterval = 5 * time.Second
+
+// Command is a persisted account operation
+type Command struct {
+	// ID orders commands by the time they were made
+	ID   string      `json:"id"`
+	Type CommandType `json:"type"`
+	// Account to create, or the account with changed attributes to update. Deletes only hold the ID.
+	Account accountapi.Account `json:"account"`
+	// Version of the account to delete
+	Version    int       `json:"version,omitempty"`
+	EnqueuedOn time.Time `json:"enqueued_on"`
+	// Attempts counts failed attempts
+	Attempts    int       `json:"attempts,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	// DeadLettered is set when the command failed for good. Neither it nor later commands of the
+	// same account are processed until it is replayed or discarded.
+	DeadLettered bool `json:"dead_lettered,omitempty"`
+}
+
+// NewCreate returns a command creating the account
+func NewCreate(account accountapi.Account) Command {
+	return newCommand(Create, account, 0)
+}
+
+// NewUpdate returns a command updating the account with its non-empty attributes
+func NewUpdate(account accountapi.Account) Command {
+	return newCommand(Update, account, 0)
+}
+
+// NewDelete returns a command deleting given version of the account
+func NewDelete(accountID string, version int) Command {
+	return newCommand(Delete, accountapi.Account{ID: accountID}, version)
+}
+
+var ids struct {
+	sync.Mutex
+	last int64
+}
+
+// newCommand returns a command with an ID that sorts after IDs of earlier commands
+func newCommand(commandType CommandType, account accountapi.Account, version int) Command {
+	now := time.Now()
+	ids.Lock()
+	nanos := now.UnixNano()
+	if nanos <= ids.last {
+		nanos = ids.last + 1
+	}
+	ids.last = nanos
+	ids.Unlock()
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return Command{
+		ID:         fmt.Sprintf("%019d-%v", nanos, hex.EncodeToString(suffix)),
+		Type:       commandType,
+		Account:    account,
+		Version:    version,
+		EnqueuedOn: now.UTC(),
+	}
+}
+
+// Store persists commands. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save inserts the command, or replaces the stored one with the same ID
+	Save(ctx context.Context, command Command) error
+	// Remove deletes the command with given ID. Removing a missing command is not an error.
+	Remove(ctx context.Context, id string) error
+	// Commands returns all stored commands, ordered by ID
+	Commands(ctx context.Context) ([]Command, error)
+}
+
+// ErrUnknownCommand is returned when replaying or discarding a command that is not dead-lettered
+var ErrUnknownCommand = errors.New("No such dead-lettered command")
+
+// Outbox sends stored commands to the account service
+type Outbox struct {
+	store   Store
+	service accountapi.AccountService
+	policy  accountapi.RetryPolicy
+	// PollInterval at which Run looks for commands saved by other processes, DefaultPollInterval when zero
+	PollInterval time.Duration
+	// Logger reports failed attempts and dead-lettered commands. Nothing is logged when nil.
+	Logger *log.Logger
+
+	// mu makes sure a command is not sent by two processing rounds of this outbox at once.
+	// Processing by other outboxes is not prevented, see the package documentation.
+	mu   sync.Mutex
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// New returns an outbox sending commands kept in store to service. Failed commands are retried
+// as long as policy allows, and dead-lettered after that.
+func New(store Store, service accountapi.AccountService, policy accountapi.RetryPolicy) *Outbox {
+	return &Outbox{store: store, service: service, policy: policy, wake: make(chan struct{}, 1), now: time.Now}
+}
+
+// Create stores a command creating the account
+func (outbox *Outbox) Create(ctx context.Context, account accountapi.Account) (Command, error) {
+	return outbox.Enqueue(ctx, NewCreate(account))
+}
+
+// Update stores a command updating the account with its non-empty attributes
+func (outbox *Outbox) Update(ctx context.Context, account accountapi.Account) (Command, error) {
+	return outbox.Enqueue(ctx, NewUpdate(account))
+}
+
+// Delete stores a command deleting given version of the account
+func (outbox *Outbox) Delete(ctx context.Context, accountID string, version int) (Command, error) {
+	return outbox.Enqueue(ctx, NewDelete(accountID, version))
+}
+
+// Enqueue stores the command and wakes Run to process it. Once Enqueue returns without an
+// error, the command is processed even if the process stops.
+func (outbox *Outbox) Enqueue(ctx context.Context, command Command) (Command, error) {
+	if command.ID == "" {
+		return command, fmt.Errorf("Command has no ID, create it with NewCreate, NewUpdate or NewDelete")
+	}
+	if err := outbox.store.Save(ctx, command); err != nil {
+		return command, fmt.Errorf("Could not store %v command of account %v: %v", command.Type, command.Account.ID, err)
+	}
+	outbox.notify()
+	return command, nil
+}
+
+func (outbox *Outbox) notify() {
+	select {
+	case outbox.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes commands as they are enqueued or become due for a retry, until ctx is done.
+// No other outbox may process the store meanwhile.
+func (outbox *Outbox) Run(ctx context.Context) error {
+	pollInterval := outbox.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	for {
+		next, err := outbox.process(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			outbox.logf("Could not process outbox: %v", err)
+		}
+
+		wait := pollInterval
+		if !next.IsZero() && next.Sub(outbox.now()) < wait {
+			wait = next.Sub(outbox.now())
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-outbox.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Process sends all commands that are due once, in the order they were enqueued, and returns.
+// It suits services that process the outbox from a scheduled job instead of with Run.
+func (outbox *Outbox) Process(ctx context.Context) error {
+	_, err := outbox.process(ctx)
+	return err
+}
+
+// process sends due commands and returns when the next retry is due, zero when none is waiting.
+// A command waiting for a retry or dead-lettered holds back later commands of the same account,
+// so that e.g. an update is not sent before the create it follows.
+func (outbox *Outbox) process(ctx context.Context) (time.Time, error) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	commands, err := outbox.store.Commands(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var next time.Time
+	held := map[string]bool{}
+	for _, command := range commands {
+		if command.DeadLettered || held[command.Account.ID] {
+			held[command.Account.ID] = true
+			continue
+		}
+		if outbox.now().Before(command.NextAttempt) {
+			held[command.Account.ID] = true
+			next = earliest(next, command.NextAttempt)
+			continue
+		}
+
+		err := outbox.send(ctx, command)
+		if ctx.Err() != nil {
+			// An attempt cut short by the caller does not count
+			return next, ctx.Err()
+		}
+		if err == nil {
+			if err := outbox.store.Remove(ctx, command.ID); err != nil {
+				return next, fmt.Errorf("Could not remove sent command %v: %v", command.ID, err)
+			}
+			continue
+		}
+
+		command.Attempts++
+		command.LastError = err.Error()
+		if outbox.policy.ShouldRetry(command.Attempts, err) {
+			command.NextAttempt = outbox.now().Add(outbox.policy.Delay(command.Attempts))
+			held[command.Account.ID] = true
+			next = earliest(next, command.NextAttempt)
+			outbox.logf("Could not %v account %v, attempt %v: %v", command.Type, command.Account.ID, command.Attempts, err)
+		} else {
+			command.DeadLettered = true
+			held[command.Account.ID] = true
+			outbox.logf("Could not %v account %v, dead-lettered command %v: %v", command.Type, command.Account.ID, command.ID, err)
+		}
+		if err := outbox.store.Save(ctx, command); err != nil {
+			return next, fmt.Errorf("Could not store failed command %v: %v", command.ID, err)
+		}
+	}
+	return next, nil
+}
+
+// send executes the command. A create whose account already exists and a delete of an account
+// that does not exist succeed, since an earlier attempt may have been made without its response
+// being received.
+func (outbox *Outbox) send(ctx context.Context, command Command) error {
+	var err error
+	var apiErr *accountapi.APIError
+	switch command.Type {
+	case Create:
+		_, err = outbox.service.CreateAccountContext(ctx, command.Account)
+		if errors.As(err, &apiErr) && apiErr.IsConflict() {
+			return nil
+		}
+	case Update:
+		_, err = outbox.service.UpdateAccountContext(ctx, command.Account)
+	case Delete:
+		err = outbox.service.DeleteAccountContext(ctx, command.Account.ID, command.Version)
+		if errors.As(err, &apiErr) && apiErr.IsNotFound() {
+			return nil
+		}
+	default:
+		err = fmt.Errorf("Unknown command type %q", command.Type)
+	}
+	return err
+}
+
+func earliest(current time.Time, candidate time.Time) time.Time {
+	if current.IsZero() || candidate.Before(current) {
+		return candidate
+	}
+	return current
+}
+
+// Pending returns commands that were not sent yet or wait for a retry
+func (outbox *Outbox) Pending(ctx context.Context) ([]Command, error) {
+	return outbox.commands(ctx, false)
+}
+
+// DeadLetters returns commands that failed for good, with the error of their last attempt
+func (outbox *Outbox) DeadLetters(ctx context.Context) ([]Command, error) {
+	return outbox.commands(ctx, true)
+}
+
+func (outbox *Outbox) commands(ctx context.Context, deadLettered bool) ([]Command, error) {
+	commands, err := outbox.store.Commands(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var selected []Command
+	for _, command := range commands {
+		if command.DeadLettered == deadLettered {
+			selected = append(selected, command)
+		}
+	}
+	return selected, nil
+}
+
+// Replay makes a dead-lettered command pending again, with all attempts of the retry policy.
+// It keeps its place in the order of commands.
+func (outbox *Outbox) Replay(ctx context.Context, id string) error {
+	command, err := outbox.deadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	command.DeadLettered = false
+	command.Attempts = 0
+	command.NextAttempt = time.Time{}
+	if err := outbox.store.Save(ctx, command); err != nil {
+		return err
+	}
+	outbox.notify()
+	return nil
+}
+
+// Discard removes a dead-lettered command without sending it
+func (outbox *Outbox) Discard(ctx context.Context, id string) error {
+	if _, err := outbox.deadLetter(ctx, id); err != nil {
+		return err
+	}
+	return outbox.store.Remove(ctx, id)
+}
+
+func (outbox *Outbox) deadLetter(ctx context.Context, id string) (Command, error) {
+	deadLetters, err := outbox.DeadLetters(ctx)
+	if err != nil {
+		return Command{}, err
+	}
+	for _, command := range deadLetters {
+		if command.ID == id {
+			return command, nil
+		}
+	}
+	return Command{}, fmt.Errorf("%w: %v", ErrUnknownCommand, id)
+}
+
+func (outbox *Outbox) logf(format string, args ...interface{}) {
+	if outbox.Logger != nil {
+		outbox.Logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,234 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+	"github.com/dexpetkovic/zero-one-go/src/internal/sqltest"
+)
+
+const (
+	organisationID = "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	ukAccountID    = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	nlAccountID    = "bf33e333-9605-4b4b-a0e5-3003ea9cc4dc"
+)
+
+func newAccount(id string, country string, bic string) accountapi.Account {
+	account := accountapi.Account{Type: "accounts", ID: id, OrganisationID: organisationID}
+	account.Attributes.Country = country
+	account.Attributes.Bic = bic
+	return account
+}
+
+// newFileStore returns a store in a directory that is removed after the test
+func newFileStore(t *testing.T) *FileStore {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "outbox"))
+	if err != nil {
+		t.Fatalf("Could not create store: %v", err)
+	}
+	return store
+}
+
+// clock is a manually advanced time source
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	store := newFileStore(t)
+	service := accountapitest.NewMemory()
+	outbox := New(store, service, accountapi.RetryPolicy{})
+
+	t.Run("Test commands are sent in order", func(t *testing.T) {
+		outbox.Create(ctx, newAccount(ukAccountID, "GB", "NWBKGB22"))
+		update := accountapi.Account{Type: "accounts", ID: ukAccountID}
+		update.Attributes.Bic = "NWBKGB42"
+		outbox.Update(ctx, update)
+		outbox.Create(ctx, newAccount(nlAccountID, "NL", "NLABNA01"))
+		outbox.Delete(ctx, nlAccountID, 0)
+
+		if pending, _ := outbox.Pending(ctx); len(pending) != 4 || pending[0].Type != Create || pending[3].Type != Delete {
+			t.Fatalf("Commands should be pending in order: %+v", pending)
+		}
+		if err := outbox.Process(ctx); err != nil {
+			t.Fatalf("Processing failed: %v", err)
+		}
+		accounts := service.Accounts()
+		if len(accounts) != 1 || accounts[0].Attributes.Bic != "NWBKGB42" || accounts[0].Version != 1 {
+			t.Errorf("Accounts do not match: %+v", accounts)
+		}
+		if pending, _ := outbox.Pending(ctx); len(pending) != 0 {
+			t.Errorf("Sent commands should be removed: %+v", pending)
+		}
+	})
+
+	t.Run("Test repeated commands succeed", func(t *testing.T) {
+		// The account was created by an attempt whose response was lost
+		outbox.Create(ctx, newAccount(ukAccountID, "GB", "NWBKGB22"))
+		outbox.Delete(ctx, nlAccountID, 0)
+		outbox.Process(ctx)
+		if pending, _ := outbox.Pending(ctx); len(pending) != 0 {
+			t.Errorf("Existing account should not be created again: %+v", pending)
+		}
+		if deadLetters, _ := outbox.DeadLetters(ctx); len(deadLetters) != 0 {
+			t.Errorf("Commands should not be dead-lettered: %+v", deadLetters)
+		}
+	})
+}
+
+func TestRetryAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := newFileStore(t)
+	service := accountapitest.NewMemory()
+	policy := accountapi.RetryPolicy{MaxAttempts: 2, InitialInterval: time.Minute}
+	outbox := New(store, service, policy)
+	now := &clock{time.Now()}
+	outbox.now = now.Now
+
+	account := newAccount(ukAccountID, "GB", "NWBKGB22")
+	outbox.Create(ctx, account)
+	update := accountapi.Account{Type: "accounts", ID: ukAccountID}
+	update.Attributes.Bic = "NWBKGB42"
+	outbox.Update(ctx, update)
+
+	service.FailNext(2, http.StatusServiceUnavailable, "maintenance")
+	outbox.Process(ctx)
+	pending, _ := outbox.Pending(ctx)
+	if len(pending) != 2 || pending[0].Attempts != 1 || pending[0].LastError == "" || !pending[0].NextAttempt.Equal(now.now.Add(time.Minute)) {
+		t.Fatalf("Failed command should wait for a retry: %+v", pending)
+	}
+	if pending[1].Attempts != 0 {
+		t.Errorf("Update should wait for the create: %+v", pending[1])
+	}
+
+	outbox.Process(ctx)
+	if pending, _ := outbox.Pending(ctx); pending[0].Attempts != 1 {
+		t.Errorf("Command should not be retried before it is due: %+v", pending[0])
+	}
+
+	now.now = now.now.Add(time.Minute)
+	outbox.Process(ctx)
+	deadLetters, err := outbox.DeadLetters(ctx)
+	if err != nil || len(deadLetters) != 1 || deadLetters[0].Type != Create || deadLetters[0].Attempts != 2 {
+		t.Fatalf("Command should be dead-lettered after all attempts: %+v %v", deadLetters, err)
+	}
+	if pending, _ := outbox.Pending(ctx); len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("Update should wait for the dead-lettered create: %+v", pending)
+	}
+
+	t.Run("Test replay", func(t *testing.T) {
+		if err := outbox.Replay(ctx, deadLetters[0].ID); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		outbox.Process(ctx)
+		if accounts := service.Accounts(); len(accounts) != 1 || accounts[0].Attributes.Bic != "NWBKGB42" {
+			t.Errorf("Replayed command and the held update should be sent: %+v", accounts)
+		}
+		if deadLetters, _ := outbox.DeadLetters(ctx); len(deadLetters) != 0 {
+			t.Errorf("Replayed command should not be dead-lettered: %+v", deadLetters)
+		}
+		if err := outbox.Replay(ctx, deadLetters[0].ID); !errors.Is(err, ErrUnknownCommand) {
+			t.Errorf("Sent command should not be replayed: %v", err)
+		}
+	})
+
+	t.Run("Test errors that are not retried", func(t *testing.T) {
+		command, _ := outbox.Delete(ctx, ukAccountID, 7)
+		outbox.Process(ctx)
+		deadLetters, _ := outbox.DeadLetters(ctx)
+		if len(deadLetters) != 1 || deadLetters[0].ID != command.ID || deadLetters[0].Attempts != 1 {
+			t.Fatalf("Conflicting delete should be dead-lettered at once: %+v", deadLetters)
+		}
+		if err := outbox.Discard(ctx, command.ID); err != nil {
+			t.Errorf("Discard failed: %v", err)
+		}
+		if commands, _ := store.Commands(ctx); len(commands) != 0 {
+			t.Errorf("Discarded command should be removed: %+v", commands)
+		}
+	})
+}
+
+func TestRestart(t *testing.T) {
+	ctx := context.Background()
+	store := newFileStore(t)
+	service := accountapitest.NewMemory()
+
+	// The service stops before the stored command is sent
+	New(store, service, accountapi.RetryPolicy{}).Create(ctx, newAccount(ukAccountID, "GB", "NWBKGB22"))
+
+	restarted, err := NewFileStore(store.dir)
+	if err != nil {
+		t.Fatalf("Could not open store: %v", err)
+	}
+	if err := New(restarted, service, accountapi.RetryPolicy{}).Process(ctx); err != nil {
+		t.Fatalf("Processing failed: %v", err)
+	}
+	if accounts := service.Accounts(); len(accounts) != 1 {
+		t.Errorf("Command stored before the restart should be sent: %+v", accounts)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	service := accountapitest.NewMemory()
+	outbox := New(newFileStore(t), service, accountapi.RetryPolicy{InitialInterval: time.Millisecond})
+
+	done := make(chan error)
+	go func() { done <- outbox.Run(ctx) }()
+
+	service.FailNext(1, http.StatusTooManyRequests, "slow down")
+	outbox.Create(ctx, newAccount(ukAccountID, "GB", "NWBKGB22"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(service.Accounts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if accounts := service.Accounts(); len(accounts) != 1 {
+		t.Errorf("Run should send the command: %+v", accounts)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run should stop when the context is done: %v", err)
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	db := sqltest.Open(t)
+	ctx := context.Background()
+	store, err := NewSQLStore(ctx, db)
+	if err != nil {
+		t.Fatalf("Could not create store: %v", err)
+	}
+	service := accountapitest.NewMemory()
+	outbox := New(store, service, accountapi.RetryPolicy{})
+
+	tx, _ := db.BeginTx(ctx, nil)
+	store.SaveTx(ctx, tx, NewCreate(newAccount(ukAccountID, "GB", "NWBKGB22")))
+	tx.Rollback()
+	tx, _ = db.BeginTx(ctx, nil)
+	store.SaveTx(ctx, tx, NewCreate(newAccount(nlAccountID, "NL", "NLABNA01")))
+	tx.Commit()
+
+	service.FailNext(1, http.StatusBadRequest, "invalid")
+	outbox.Process(ctx)
+	deadLetters, err := outbox.DeadLetters(ctx)
+	if err != nil || len(deadLetters) != 1 || deadLetters[0].Account.ID != nlAccountID {
+		t.Fatalf("Only the committed command should be stored: %+v %v", deadLetters, err)
+	}
+	outbox.Replay(ctx, deadLetters[0].ID)
+	outbox.Process(ctx)
+	if commands, _ := store.Commands(ctx); len(commands) != 0 || len(service.Accounts()) != 1 {
+		t.Errorf("Replayed command should be sent and removed: %+v", commands)
+	}
+}
@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore keeps each command in a JSON file of a directory. Files are written to a temporary
+// file, synced and renamed, so a crash leaves either the old or the new command. The directory
+// is synced after renames and removals, so that they survive a crash too.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store keeping commands in dir, which is created when missing
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Could not create outbox directory: %v", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the command to <ID>.json
+func (store *FileStore) Save(ctx context.Context, command Command) error {
+	bCommand, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(store.dir, command.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(bCommand)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), store.path(command.ID))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return store.syncDir()
+}
+
+// Remove deletes the file of the command
+func (store *FileStore) Remove(ctx context.Context, id string) error {
+	err := os.Remove(store.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return store.syncDir()
+}
+
+// syncDir writes changes of directory entries to disk
+func (store *FileStore) syncDir() error {
+	dir, err := os.Open(store.dir)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Commands reads all command files
+func (store *FileStore) Commands(ctx context.Context) ([]Command, error) {
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+	var commands []Command
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		bCommand, err := ioutil.ReadFile(filepath.Join(store.dir, file.Name()))
+		if os.IsNotExist(err) {
+			// Removed since the directory was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var command Command
+		if err := json.Unmarshal(bCommand, &command); err != nil {
+			return nil, fmt.Errorf("Invalid outbox file %v: %v", file.Name(), err)
+		}
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].ID < commands[j].ID })
+	return commands, nil
+}
+
+func (store *FileStore) path(id string) string {
+	return filepath.Join(store.dir, id+".json")
+}
+
+// SQLStore keeps commands in the outbox table of a SQL database, in the SQLite dialect.
+// Commands saved with SaveTx are committed together with the caller's own changes, so a
+// command is stored exactly when the change that required it is.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates the outbox table in db unless it exists, and returns the store
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS outbox (id TEXT PRIMARY KEY, command TEXT NOT NULL)")
+	if err != nil {
+		return nil, fmt.Errorf("Could not create outbox table: %v", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+const saveStatement = "INSERT INTO outbox (id, command) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET command = excluded.command"
+
+// Save inserts or replaces the command
+func (store *SQLStore) Save(ctx context.Context, command Command) error {
+	bCommand, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	_, err = store.db.ExecContext(ctx, saveStatement, command.ID, string(bCommand))
+	return err
+}
+
+// SaveTx inserts the command within the caller's transaction. It is processed once the
+// transaction is committed, by the next round of Run.
+func (store *SQLStore) SaveTx(ctx context.Context, tx *sql.Tx, command Command) error {
+	bCommand, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, saveStatement, command.ID, string(bCommand))
+	return err
+}
+
+// Remove deletes the command
+func (store *SQLStore) Remove(ctx context.Context, id string) error {
+	_, err := store.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = ?", id)
+	return err
+}
+
+// Commands reads all stored commands
+func (store *SQLStore) Commands(ctx context.Context) ([]Command, error) {
+	rows, err := store.db.QueryContext(ctx, "SELECT command FROM outbox ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []Command
+	for rows.Next() {
+		var bCommand string
+		var command Command
+		if err := rows.Scan(&bCommand); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(bCommand), &command); err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+	return commands, rows.Err()
+}
@@ -0,0 +1,108 @@
+package accountapi
+
+import "context"
+
+// AccountIterator pages through listed accounts, fetching the next page when the current one is used up.
+// Accounts are decoded one at a time while the response of their page is read, so a page is never
+// held in memory as a whole. The Timeout of the HTTP client covers reading a page, so accounts
+// that take long to handle are better collected first.
+//
+//	it := config.IterateAccounts(ctx, 100, nil)
+//	defer it.Close()
+//	for it.Next() {
+//		account := it.Account()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type AccountIterator struct {
+	config   Configuration
+	ctx      context.Context
+	pageSize int
+	filter   Filter
+
+	page   int
+	stream *pageStream[Account]
+	// read counts accounts read from the current page
+	read    int
+	account Account
+	err     error
+	done    bool
+}
+
+// IterateAccounts returns an iterator over all accounts matching given filter,
+// requesting pageSize accounts at a time
+func (config Configuration) IterateAccounts(ctx context.Context, pageSize int, filter Filter) *AccountIterator {
+	if pageSize < 1 {
+		pageSize = 100
+	}
+	return &AccountIterator{config: config, ctx: ctx, pageSize: pageSize, filter: filter}
+}
+
+// Next advances to the next account and reports whether there is one.
+// It returns false at the end of the list or when a page could not be fetched or decoded.
+// Pages are decoded while they are read, so accounts of a page that fails part way, e.g. with
+// a *ResponseTooLargeError, are yielded before Next returns false.
+func (it *AccountIterator) Next() bool {
+	for it.err == nil {
+		if it.stream == nil {
+			if it.done {
+				return false
+			}
+			body, err := it.config.Accounts().openList(it.ctx, it.page, it.pageSize, it.filter)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.stream = newPageStream[Account](body)
+			it.page++
+			it.read = 0
+		}
+
+		account, ok, err := it.stream.next()
+		if err != nil {
+			it.err = err
+			it.Close()
+			return false
+		}
+		if ok {
+			it.read++
+			it.account = account
+			return true
+		}
+
+		// The last page has no next link. APIs that do not send links end with a short page.
+		links := it.stream.document.Links
+		it.Close()
+		if links != nil {
+			it.done = links["next"] == ""
+		} else if it.read < it.pageSize {
+			it.done = true
+		}
+		if it.read == 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// Account returns the current account
+func (it *AccountIterator) Account() Account {
+	return it.account
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *AccountIterator) Err() error {
+	return it.err
+}
+
+// Close releases the response of the current page. It is only needed when the iteration is
+// stopped before Next returns false, and may be called any number of times.
+func (it *AccountIterator) Close() error {
+	if it.stream == nil {
+		return nil
+	}
+	err := it.stream.close()
+	it.stream = nil
+	return err
+}
@@ -0,0 +1,135 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestIterateAccounts(t *testing.T) {
+	var requestedPages []string
+	total := 5
+	withLinks := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("page[size]"))
+		requestedPages = append(requestedPages, r.URL.Query().Get("page[number]"))
+
+		var body Document[[]Account]
+		for i := page * size; i < total && i < (page+1)*size; i++ {
+			account := validUkAccount
+			account.ID = fmt.Sprint(i)
+			body.Data = append(body.Data, account)
+		}
+		if withLinks {
+			body.Links = Links{"self": r.URL.String()}
+			if (page+1)*size < total {
+				body.Links["next"] = fmt.Sprintf("%v?page[number]=%v&page[size]=%v", r.URL.Path, page+1, size)
+			}
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer ts.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: ts.URL + "/v1/organisation/accounts/"}
+
+	it := testConfig.IterateAccounts(context.Background(), 2, nil)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Account().ID)
+	}
+	if it.Err() != nil {
+		t.Errorf("Error while iterating accounts: %v", it.Err())
+	}
+	if fmt.Sprint(ids) != "[0 1 2 3 4]" {
+		t.Errorf("Iterated accounts do not match: %v", ids)
+	}
+	if fmt.Sprint(requestedPages) != "[0 1 2]" {
+		t.Errorf("Unexpected pages requested: %v", requestedPages)
+	}
+
+	// When the last page is full, one more empty page is requested
+	requestedPages = nil
+	total = 4
+	it = testConfig.IterateAccounts(context.Background(), 2, nil)
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if count != 4 || fmt.Sprint(requestedPages) != "[0 1 2]" {
+		t.Errorf("Unexpected iteration over full pages: %v accounts from pages %v", count, requestedPages)
+	}
+
+	// Links tell that a full page is the last one, so no empty page is requested
+	requestedPages = nil
+	withLinks = true
+	it = testConfig.IterateAccounts(context.Background(), 2, nil)
+	count = 0
+	for it.Next() {
+		count++
+	}
+	if count != 4 || fmt.Sprint(requestedPages) != "[0 1]" {
+		t.Errorf("Unexpected iteration with links: %v accounts from pages %v", count, requestedPages)
+	}
+}
+
+func TestIterateAccountsStreaming(t *testing.T) {
+	firstRead := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusOK)
+		bAccount, _ := json.Marshal(validUkAccount)
+		fmt.Fprintf(w, `{"meta":{"total":2},"data":[%s`, bAccount)
+		w.(http.Flusher).Flush()
+		// The rest of the page is only sent once the first account was iterated
+		<-firstRead
+		fmt.Fprintf(w, `,%s],"links":{"self":"%v"}}`, bAccount, r.URL)
+	}))
+	defer ts.Close()
+	testConfig := Configuration{AccountAPIUrl: ts.URL + "/v1/organisation/accounts/"}
+
+	it := testConfig.IterateAccounts(context.Background(), 10, nil)
+	defer it.Close()
+	if !it.Next() || it.Account().ID != validUkAccount.ID {
+		t.Fatalf("First account should be read before the page is complete: %v", it.Err())
+	}
+	close(firstRead)
+	count := 1
+	for it.Next() {
+		count++
+	}
+	if count != 2 || it.Err() != nil {
+		t.Errorf("Unexpected iteration: %v accounts, %v", count, it.Err())
+	}
+
+	t.Run("Test Invalid Pages", func(t *testing.T) {
+		for _, body := range []string{`{"data":{"id":"1"}}`, `{"data":[{"id":1}]}`, `[]`, `{"data":[`} {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, body)
+			}))
+			it := Configuration{AccountAPIUrl: ts.URL + "/v1/organisation/accounts/"}.IterateAccounts(context.Background(), 10, nil)
+			if it.Next() || it.Err() == nil {
+				t.Errorf("Page %v should fail", body)
+			}
+			ts.Close()
+		}
+	})
+
+	t.Run("Test Null Data", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":null}`)
+		}))
+		defer ts.Close()
+		it := Configuration{AccountAPIUrl: ts.URL + "/v1/organisation/accounts/"}.IterateAccounts(context.Background(), 10, nil)
+		if it.Next() || it.Err() != nil {
+			t.Errorf("Null data should be an empty page: %v", it.Err())
+		}
+	})
+}
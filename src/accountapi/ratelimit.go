@@ -0,0 +1,107 @@
+package accountapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errLimiterStopped is returned by wait once the limiter is stopped
+var errLimiterStopped = errors.New("Rate limiter is stopped")
+
+// rateLimiter spaces out requests so that at most a given number start per second, and holds
+// them back while the API asked to pause, e.g. with a Retry-After header.
+// A nil rateLimiter does not limit anything.
+type rateLimiter struct {
+	// ticker is nil when the rate is not limited
+	ticker *time.Ticker
+	done   chan struct{}
+	once   sync.Once
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// newRateLimiter returns a limiter for given rate, not limiting the rate when it is not positive
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	limiter := &rateLimiter{done: make(chan struct{})}
+	if requestsPerSecond > 0 {
+		interval := time.Duration(float64(time.Second) / requestsPerSecond)
+		if interval <= 0 {
+			interval = time.Nanosecond
+		}
+		limiter.ticker = time.NewTicker(interval)
+	}
+	return limiter
+}
+
+// wait blocks until the next request is allowed, ctx is done or the limiter is stopped
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-limiter.done:
+		return errLimiterStopped
+	default:
+	}
+	if limiter.ticker != nil {
+		select {
+		case <-limiter.ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-limiter.done:
+			return errLimiterStopped
+		}
+	}
+	// A pause may start while waiting for the ticker
+	for {
+		delay := limiter.pausedFor()
+		if delay <= 0 {
+			return ctx.Err()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-limiter.done:
+			timer.Stop()
+			return errLimiterStopped
+		}
+	}
+}
+
+// pause holds back requests for given duration, unless they are held back longer already
+func (limiter *rateLimiter) pause(delay time.Duration) {
+	if limiter == nil {
+		return
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(limiter.pausedUntil) {
+		limiter.pausedUntil = until
+	}
+}
+
+// pausedFor returns how long requests are still held back
+func (limiter *rateLimiter) pausedFor() time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return time.Until(limiter.pausedUntil)
+}
+
+// stop releases resources held by the limiter. Waiting and later calls of wait fail with
+// errLimiterStopped, as the ticker never ticks again.
+func (limiter *rateLimiter) stop() {
+	if limiter != nil {
+		limiter.once.Do(func() {
+			if limiter.ticker != nil {
+				limiter.ticker.Stop()
+			}
+			close(limiter.done)
+		})
+	}
+}
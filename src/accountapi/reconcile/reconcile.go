@@ -0,0 +1,275 @@
+// Package reconcile manages accounts declaratively. Given the desired set of accounts, e.g. kept
+// in git as a YAML or JSON file, it plans which accounts to create, update and delete so that
+// the API holds exactly that set, and applies the plan.
+//
+//	desired, err := reconcile.LoadFile("accounts.yaml")
+//	...
+//	plan, err := reconcile.MakePlan(ctx, config, desired, reconcile.Options{})
+//	...
+//	fmt.Print(plan)
+//	applied, err := reconcile.Apply(ctx, config, plan)
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountdiff"
+	"gopkg.in/yaml.v3"
+)
+
+// ActionType tells what an action does to an account
+type ActionType string
+
+// Action types, applied in this order
+const (
+	Create ActionType = "create"
+	Update ActionType = "update"
+	Delete ActionType = "delete"
+)
+
+// actionSymbols prefix actions in rendered plans
+var actionSymbols = map[ActionType]string{Create: "+", Update: "~", Delete: "-"}
+
+// Action is a planned change of one account. Current is the listed account, empty for creates;
+// Desired is empty for deletes. Changes lead from Current to Desired.
+type Action struct {
+	Type    ActionType            `json:"action"`
+	ID      string                `json:"id"`
+	Current accountapi.Account    `json:"-"`
+	Desired accountapi.Account    `json:"-"`
+	Changes accountdiff.ChangeSet `json:"changes"`
+}
+
+// String renders the action followed by its changes, indented
+func (action Action) String() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%v %v account %v", actionSymbols[action.Type], action.Type, action.ID)
+	if action.Type != Create {
+		fmt.Fprintf(&out, " (version %v)", action.Current.Version)
+	}
+	out.WriteString("\n")
+	if action.Type != Delete {
+		for _, change := range action.Changes {
+			out.WriteString("    " + change.String() + "\n")
+		}
+	}
+	return out.String()
+}
+
+// Plan lists actions that turn the listed accounts into the desired ones
+type Plan struct {
+	Actions []Action `json:"actions"`
+	// Unchanged counts desired accounts that already match
+	Unchanged int `json:"unchanged"`
+}
+
+// Empty reports whether the accounts already are in the desired state
+func (plan Plan) Empty() bool {
+	return len(plan.Actions) == 0
+}
+
+// Count returns the number of planned actions of given type
+func (plan Plan) Count(actionType ActionType) int {
+	count := 0
+	for _, action := range plan.Actions {
+		if action.Type == actionType {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders all actions and a summary
+func (plan Plan) String() string {
+	var out strings.Builder
+	for _, action := range plan.Actions {
+		out.WriteString(action.String())
+	}
+	if plan.Empty() {
+		fmt.Fprintf(&out, "No changes, %v accounts are in the desired state.\n", plan.Unchanged)
+		return out.String()
+	}
+	fmt.Fprintf(&out, "Plan: %v to create, %v to update, %v to delete, %v unchanged.\n",
+		plan.Count(Create), plan.Count(Update), plan.Count(Delete), plan.Unchanged)
+	return out.String()
+}
+
+// Options of MakePlan
+type Options struct {
+	// Filter limits the accounts that are managed. Listed accounts that are not desired are deleted,
+	// so without a filter, all accounts of the API are managed.
+	Filter accountapi.Filter
+	// Ignore lists fields the API manages besides version and timestamps, e.g. "attributes.status",
+	// so that their values do not cause updates
+	Ignore []string
+	// PageSize of listed pages, 100 when zero
+	PageSize int
+}
+
+// MakePlan lists the managed accounts and compares them with the desired ones, by ID.
+// Desired accounts need an ID; their type defaults to "accounts". Relationships of accounts
+// that do not desire any are not managed, as the API sets them, e.g. to the master account.
+// Changes that a patch can not make, like moving an account to another organisation, are an error.
+func MakePlan(ctx context.Context, config accountapi.Configuration, desired []accountapi.Account, options Options) (Plan, error) {
+	desiredByID := map[string]accountapi.Account{}
+	for i, account := range desired {
+		if account.ID == "" {
+			return Plan{}, fmt.Errorf("Desired account %v has no ID", i+1)
+		}
+		if _, exists := desiredByID[account.ID]; exists {
+			return Plan{}, fmt.Errorf("Account %v is desired more than once", account.ID)
+		}
+		if account.Type == "" {
+			account.Type = "accounts"
+		}
+		desiredByID[account.ID] = account
+	}
+
+	current := map[string]accountapi.Account{}
+	it := config.IterateAccounts(ctx, options.PageSize, options.Filter)
+	for it.Next() {
+		current[it.Account().ID] = it.Account()
+	}
+	if err := it.Err(); err != nil {
+		return Plan{}, err
+	}
+
+	diffOptions := accountdiff.Options{IgnoreServerManaged: true, Ignore: options.Ignore}
+	var plan Plan
+	for id, account := range desiredByID {
+		existing, exists := current[id]
+		if !exists {
+			changes := accountdiff.Diff(accountapi.Account{}, account, diffOptions)
+			plan.Actions = append(plan.Actions, Action{Create, id, accountapi.Account{}, account, changes})
+			continue
+		}
+		accountOptions := diffOptions
+		if len(account.Relationships) == 0 {
+			accountOptions.Ignore = append(options.Ignore[:len(options.Ignore):len(options.Ignore)], "relationships")
+		}
+		changes := accountdiff.Diff(existing, account, accountOptions)
+		if changes.Empty() {
+			plan.Unchanged++
+			continue
+		}
+		if _, err := changes.Patch(existing); err != nil {
+			return Plan{}, fmt.Errorf("Account %v can not be updated: %v", id, err)
+		}
+		plan.Actions = append(plan.Actions, Action{Update, id, existing, account, changes})
+	}
+	for id, account := range current {
+		if _, exists := desiredByID[id]; !exists {
+			plan.Actions = append(plan.Actions, Action{Delete, id, account, accountapi.Account{}, nil})
+		}
+	}
+
+	order := map[ActionType]int{Create: 0, Update: 1, Delete: 2}
+	sort.Slice(plan.Actions, func(i, j int) bool {
+		if plan.Actions[i].Type != plan.Actions[j].Type {
+			return order[plan.Actions[i].Type] < order[plan.Actions[j].Type]
+		}
+		return plan.Actions[i].ID < plan.Actions[j].ID
+	})
+	return plan, nil
+}
+
+// Apply executes the actions of the plan in order and returns the applied ones. Updates and
+// deletes are made to the planned version, so accounts changed since planning fail with a
+// conflict instead of being overwritten. Applying stops at the first failed action.
+func Apply(ctx context.Context, config accountapi.Configuration, plan Plan) ([]Action, error) {
+	var applied []Action
+	for _, action := range plan.Actions {
+		var err error
+		switch action.Type {
+		case Create:
+			_, err = config.CreateAccountContext(ctx, action.Desired)
+		case Update:
+			var patch accountdiff.Patch
+			if patch, err = action.Changes.Patch(action.Current); err == nil {
+				_, err = config.Accounts().Patch(ctx, action.ID, patch)
+			}
+		case Delete:
+			err = config.DeleteAccountContext(ctx, action.ID, action.Current.Version)
+		}
+		if err != nil {
+			return applied, fmt.Errorf("Could not %v account %v: %w", action.Type, action.ID, err)
+		}
+		applied = append(applied, action)
+	}
+	return applied, nil
+}
+
+// ErrNoAccounts is wrapped by errors of LoadFile when the file holds no accounts
+var ErrNoAccounts = errors.New("no accounts are desired")
+
+// LoadFile reads desired accounts from a YAML or JSON file, chosen by its extension. The file
+// holds a list of accounts, a single account, or a document with the list in "data" or "accounts", like
+//
+//	accounts:
+//	  - id: ad27e265-9605-4b4b-a0e5-3003ea9cc4dc
+//	    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+//	    attributes:
+//	      country: GB
+//	      bic: NWBKGB22
+//
+// Unknown fields are an error, so that misspelled attributes are not silently dropped. A file
+// without accounts would delete all managed accounts, so it is an error wrapping ErrNoAccounts;
+// callers that mean to delete them check for it with errors.Is.
+func LoadFile(path string) ([]accountapi.Account, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".json":
+		err = json.Unmarshal(data, &document)
+	default:
+		err = fmt.Errorf("unsupported format, use .yaml or .json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid desired state file %v: %v", path, err)
+	}
+
+	if mapping, ok := document.(map[string]interface{}); ok {
+		switch {
+		case mapping["data"] != nil:
+			document = mapping["data"]
+		case mapping["accounts"] != nil:
+			document = mapping["accounts"]
+		case mapping["id"] != nil:
+			document = []interface{}{mapping}
+		}
+	}
+	if document == nil {
+		return nil, fmt.Errorf("Invalid desired state file %v: %w", path, ErrNoAccounts)
+	}
+	if _, ok := document.([]interface{}); !ok {
+		return nil, fmt.Errorf("Invalid desired state file %v: expected a list of accounts", path)
+	}
+	var accounts []accountapi.Account
+	bAccounts, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid desired state file %v: %v", path, err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(bAccounts)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&accounts); err != nil {
+		return nil, fmt.Errorf("Invalid desired state file %v: %v", path, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("Invalid desired state file %v: %w", path, ErrNoAccounts)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,214 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountdiff"
+)
+
+const (
+	organisationID = "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	keptID         = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	changedID      = "bf33e333-9605-4b4b-a0e5-3003ea9cc4dc"
+	createdID      = "0d1f1e4c-1c1e-4a8e-9b0a-3c9f1a2b3c4d"
+	deletedID      = "6a1f3c9e-0f42-4f1b-9f57-1f5d3c2b8a11"
+)
+
+const desiredYAML = `# Accounts of the UK organisation
+accounts:
+  - id: ad27e265-9605-4b4b-a0e5-3003ea9cc4dc
+    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+    attributes:
+      country: GB
+      bic: NWBKGB22
+  - id: "bf33e333-9605-4b4b-a0e5-3003ea9cc4dc"
+    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+    attributes:
+      country: 'GB'
+      bic: NWBKGB42 # changed
+      name:
+        - Jane Doe
+        - "J. Doe"
+  - id: 0d1f1e4c-1c1e-4a8e-9b0a-3c9f1a2b3c4d
+    organisation_id: eb0bd6f5-c3f5-44b2-b677-acd23cdde73c
+    attributes:
+      country: GB
+      joint_account: true
+      alternative_names: []
+`
+
+func newAccount(id string, bic string) accountapi.Account {
+	account := accountapi.Account{Type: "accounts", ID: id, OrganisationID: organisationID}
+	account.Attributes.Country = "GB"
+	account.Attributes.Bic = bic
+	return account
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not write %v: %v", name, err)
+	}
+	return file
+}
+
+func TestLoadFile(t *testing.T) {
+	accounts, err := LoadFile(writeFile(t, "accounts.yaml", desiredYAML))
+	if err != nil {
+		t.Fatalf("Could not load desired state: %v", err)
+	}
+	if len(accounts) != 3 || accounts[1].ID != changedID || accounts[1].Attributes.Bic != "NWBKGB42" ||
+		!reflect.DeepEqual(accounts[1].Attributes.Name, []string{"Jane Doe", "J. Doe"}) || !accounts[2].Attributes.JointAccount {
+		t.Errorf("Loaded accounts do not match: %+v", accounts)
+	}
+
+	// JSON documents are read the same way
+	bJSON, _ := json.Marshal(map[string]interface{}{"data": accounts})
+	fromJSON, err := LoadFile(writeFile(t, "accounts.json", string(bJSON)))
+	if err != nil || len(fromJSON) != len(accounts) {
+		t.Fatalf("Accounts loaded from JSON do not match: %+v %v", fromJSON, err)
+	}
+	for i := range accounts {
+		if changes := accountdiff.Diff(accounts[i], fromJSON[i], accountdiff.Options{}); !changes.Empty() {
+			t.Errorf("Account loaded from JSON does not match:\n%v", changes)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"misspelled.yaml": "- id: " + keptID + "\n  attributes:\n    contry: GB\n",
+		"indented.yaml":   "accounts:\n  - id: " + keptID + "\n     type: accounts\n",
+		"accounts.txt":    "[]",
+	} {
+		if _, err := LoadFile(writeFile(t, name, content)); err == nil {
+			t.Errorf("Loading %v should fail", name)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"empty.yaml": "# no accounts yet\n",
+		"list.yaml":  "accounts: []\n",
+		"list.json":  "[]",
+		"data.json":  `{"accounts": []}`,
+		"null.json":  "null",
+	} {
+		if _, err := LoadFile(writeFile(t, name, content)); !errors.Is(err, ErrNoAccounts) {
+			t.Errorf("Loading %v should fail as no accounts are desired: %v", name, err)
+		}
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	ctx := context.Background()
+
+	server.Seed(newAccount(keptID, "NWBKGB22"), newAccount(changedID, "NWBKGB22"), newAccount(deletedID, "NWBKGB22"))
+	desired, err := LoadFile(writeFile(t, "accounts.yaml", desiredYAML))
+	if err != nil {
+		t.Fatalf("Could not load desired state: %v", err)
+	}
+
+	plan, err := MakePlan(ctx, config, desired, Options{})
+	if err != nil {
+		t.Fatalf("Could not plan: %v", err)
+	}
+	var actions []string
+	for _, action := range plan.Actions {
+		actions = append(actions, string(action.Type)+" "+action.ID)
+	}
+	expected := []string{"create " + createdID, "update " + changedID, "delete " + deletedID}
+	if !reflect.DeepEqual(actions, expected) || plan.Unchanged != 1 {
+		t.Fatalf("Planned actions do not match: %v", actions)
+	}
+	rendered := plan.String()
+	for _, line := range []string{
+		"~ update account " + changedID + " (version 0)\n    ~ attributes.bic: \"NWBKGB22\" -> \"NWBKGB42\"\n",
+		"    + attributes.joint_account: true\n",
+		"Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged.",
+	} {
+		if !strings.Contains(rendered, line) {
+			t.Errorf("Rendered plan is missing %q:\n%v", line, rendered)
+		}
+	}
+
+	applied, err := Apply(ctx, config, plan)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("Could not apply plan: %v", err)
+	}
+	if plan, _ := MakePlan(ctx, config, desired, Options{}); !plan.Empty() || plan.Unchanged != 3 {
+		t.Errorf("Applied plan should leave no changes:\n%v", plan)
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	ctx := context.Background()
+
+	server.Seed(newAccount(changedID, "NWBKGB22"))
+	plan, err := MakePlan(ctx, config, []accountapi.Account{newAccount(changedID, "NWBKGB42")}, Options{})
+	if err != nil || plan.Count(Update) != 1 {
+		t.Fatalf("Update should be planned: %v %v", plan, err)
+	}
+
+	// The account changes after planning
+	changed := newAccount(changedID, "NWBKGB33")
+	changed.Version = 1
+	server.Seed(changed)
+
+	applied, err := Apply(ctx, config, plan)
+	var apiErr *accountapi.APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsConflict() || len(applied) != 0 {
+		t.Errorf("Account changed since planning should conflict: %v", err)
+	}
+
+	moved := newAccount(changedID, "NWBKGB42")
+	moved.OrganisationID = "ee2fb143-6dfe-4787-b183-ca8ddd4164d2"
+	if _, err := MakePlan(ctx, config, []accountapi.Account{moved}, Options{}); err == nil {
+		t.Errorf("Changing the organisation should not be planned")
+	}
+	if _, err := MakePlan(ctx, config, []accountapi.Account{moved, moved}, Options{}); err == nil {
+		t.Errorf("Duplicate desired accounts should not be planned")
+	}
+}
+
+func TestPlanRelationships(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	ctx := context.Background()
+
+	related := newAccount(keptID, "NWBKGB22")
+	related.Relationships = accountapi.Relationships{
+		"master_account": {Data: json.RawMessage(`[{"type":"accounts","id":"` + changedID + `"}]`)},
+	}
+	server.Seed(related)
+
+	if plan, err := MakePlan(ctx, config, []accountapi.Account{newAccount(keptID, "NWBKGB22")}, Options{}); err != nil || !plan.Empty() {
+		t.Errorf("Relationships that are not desired should not be managed: %v %v", plan, err)
+	}
+	plan, err := MakePlan(ctx, config, []accountapi.Account{newAccount(keptID, "NWBKGB42")}, Options{})
+	if err != nil || plan.Count(Update) != 1 || len(plan.Actions[0].Changes) != 1 {
+		t.Fatalf("Update of an account with relationships should be planned: %v %v", plan, err)
+	}
+	if _, err := Apply(ctx, config, plan); err != nil {
+		t.Errorf("Could not apply plan: %v", err)
+	}
+
+	desired := newAccount(keptID, "NWBKGB42")
+	desired.Relationships = accountapi.Relationships{"master_account": {Data: json.RawMessage(`[]`)}}
+	if _, err := MakePlan(ctx, config, []accountapi.Account{desired}, Options{}); err == nil {
+		t.Errorf("Changing desired relationships should not be planned")
+	}
+}
@@ -0,0 +1,122 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Resource is a typed client of a resource collection of the Bank's API, e.g. accounts or organisations.
+// A new resource is added by declaring its struct, shaped like Account, and the path it is served under:
+//
+//	type Mandate struct {
+//		Type       string            `json:"type"`
+//		ID         string            `json:"id"`
+//		Attributes MandateAttributes `json:"attributes"`
+//	}
+//
+//	mandates := accountapi.NewResource[Mandate](config, "/v1/organisation/mandates/")
+type Resource[T any] struct {
+	config Configuration
+	url    string
+}
+
+// NewResource returns a client of resources under given path of the API, on the host of
+// config.AccountAPIUrl and below its gateway prefix, if any. The path must end with a slash.
+func NewResource[T any](config Configuration, path string) Resource[T] {
+	resourceURL, err := config.apiURL(path)
+	if err != nil {
+		resourceURL = path
+	}
+	return Resource[T]{config, resourceURL}
+}
+
+// URL returns the URL of the resource collection
+func (resource Resource[T]) URL() string {
+	return resource.url
+}
+
+// Create creates a resource and returns the response document
+func (resource Resource[T]) Create(ctx context.Context, data T) (Document[T], error) {
+	bRequestBody, err := encodeDocument(data)
+	if err != nil {
+		return Document[T]{}, err
+	}
+	response, err := resource.config.doPost(ctx, resource.url, bRequestBody)
+	if err != nil {
+		return Document[T]{}, err
+	}
+	return readDocument[T](response)
+}
+
+// Fetch fetches the resource with given ID
+func (resource Resource[T]) Fetch(ctx context.Context, id string) (Document[T], error) {
+	response, err := resource.config.doGet(ctx, resource.url+id, "")
+	if err != nil {
+		return Document[T]{}, err
+	}
+	return readDocument[T](response)
+}
+
+// List fetches a page of resources that match given filter. A nil filter matches all resources.
+func (resource Resource[T]) List(ctx context.Context, pageNumber int, pageSize int, filter Filter) (Document[[]T], error) {
+	response, err := resource.openList(ctx, pageNumber, pageSize, filter)
+	if err != nil {
+		return Document[[]T]{}, err
+	}
+	return readDocument[[]T](response)
+}
+
+// openList requests a page of resources and returns the response body, to be decoded while it is read
+func (resource Resource[T]) openList(ctx context.Context, pageNumber int, pageSize int, filter Filter) (io.ReadCloser, error) {
+	var queryParams = "?page[number]=" + fmt.Sprint(pageNumber) + "&page[size]=" + fmt.Sprint(pageSize) + filter.queryParams()
+	return resource.config.doGet(ctx, resource.url, queryParams)
+}
+
+// Patch sends given data, usually the type, ID, version and changed attributes, to the resource with given ID
+func (resource Resource[T]) Patch(ctx context.Context, id string, data interface{}) (Document[T], error) {
+	bRequestBody, err := encodeDocument(data)
+	if err != nil {
+		return Document[T]{}, err
+	}
+	response, err := resource.config.doPatch(ctx, resource.url+id, bRequestBody)
+	if err != nil {
+		return Document[T]{}, err
+	}
+	return readDocument[T](response)
+}
+
+// Delete deletes given version of the resource with given ID
+func (resource Resource[T]) Delete(ctx context.Context, id string, version int) error {
+	// DELETE, when successful, does not return content.
+	response, err := resource.config.doDelete(ctx, resource.url+id, "?version="+fmt.Sprint(version))
+	if err != nil {
+		return err
+	}
+	return response.Close()
+}
+
+// readDocument decodes the response body into a document and closes it. The rest of the body
+// is read first, so that the connection can be reused.
+func readDocument[T any](body io.ReadCloser) (Document[T], error) {
+	defer body.Close()
+	document, err := decodeDocument[T](body)
+	if err != nil {
+		return Document[T]{}, err
+	}
+	if _, err = io.Copy(ioutil.Discard, body); err != nil {
+		return Document[T]{}, err
+	}
+	return document, nil
+}
+
+// encodeDocument wraps data in a document to be sent as request body
+func encodeDocument(data interface{}) ([]byte, error) {
+	bRequestBody, err := json.Marshal(Document[interface{}]{Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("Marshalling error: %v", err)
+	}
+	return bRequestBody, nil
+}
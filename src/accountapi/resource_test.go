@@ -0,0 +1,144 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newResourceTestServer keeps raw resources of any collection in memory
+func newResourceTestServer() *httptest.Server {
+	var mu sync.Mutex
+	collections := map[string]map[string]json.RawMessage{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		collection := r.URL.Path[:strings.LastIndex(r.URL.Path, "/")+1]
+		id := r.URL.Path[len(collection):]
+		if collections[collection] == nil {
+			collections[collection] = map[string]json.RawMessage{}
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+
+		var body Document[json.RawMessage]
+		bReqBody, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(bReqBody, &body)
+
+		switch {
+		case r.Method == "POST":
+			var resource ResourceObject
+			json.Unmarshal(body.Data, &resource)
+			collections[collection][resource.ID] = body.Data
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(body)
+		case r.Method == "GET" && id == "":
+			var list []json.RawMessage
+			for _, resource := range collections[collection] {
+				list = append(list, resource)
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[[]json.RawMessage]{Data: list, Meta: Meta{"count": len(list)}})
+		case collections[collection][id] == nil:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_message":"record ` + id + ` does not exist"}`))
+		case r.Method == "GET":
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[json.RawMessage]{Data: collections[collection][id]})
+		case r.Method == "PATCH":
+			collections[collection][id] = body.Data
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(body)
+		case r.Method == "DELETE":
+			delete(collections[collection], id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func TestResources(t *testing.T) {
+	srv := newResourceTestServer()
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+	ctx := context.Background()
+
+	t.Run("Test Organisations", func(t *testing.T) {
+		organisations := testConfig.Organisations()
+		if organisations.URL() != srv.URL+OrganisationsPath {
+			t.Errorf("Organisations are served from %v", organisations.URL())
+		}
+
+		organisation := Organisation{Type: "organisations", ID: validUkAccount.OrganisationID}
+		organisation.Attributes.Name = "Zero One Bank"
+		created, err := organisations.Create(ctx, organisation)
+		if err != nil || created.Data.Attributes.Name != "Zero One Bank" {
+			t.Fatalf("Organisation was not created: %+v %v", created, err)
+		}
+
+		fetched, err := organisations.Fetch(ctx, organisation.ID)
+		if err != nil || fetched.Data.ID != organisation.ID {
+			t.Errorf("Organisation was not fetched: %+v %v", fetched, err)
+		}
+
+		organisation.Attributes.Name = "Zero One Go Bank"
+		patched, err := organisations.Patch(ctx, organisation.ID, organisation)
+		if err != nil || patched.Data.Attributes.Name != "Zero One Go Bank" {
+			t.Errorf("Organisation was not patched: %+v %v", patched, err)
+		}
+
+		if err := organisations.Delete(ctx, organisation.ID, 0); err != nil {
+			t.Errorf("Organisation was not deleted: %v", err)
+		}
+		if _, err := organisations.Fetch(ctx, organisation.ID); err == nil || !err.(*APIError).IsNotFound() {
+			t.Errorf("Deleted organisation should not be found, got: %v", err)
+		}
+	})
+
+	t.Run("Test Claims", func(t *testing.T) {
+		claims := testConfig.Claims()
+		claim := Claim{Type: "claims", ID: "d1c7f1f4-8b0e-4bb6-9a9f-1b7e5c0c7c11", OrganisationID: validUkAccount.OrganisationID}
+		claim.Attributes = ClaimAttributes{Country: "GB", BankID: "400300", BankIDCode: "GBDSC", AccountNumber: "41426819"}
+		if _, err := claims.Create(ctx, claim); err != nil {
+			t.Fatalf("Claim was not created: %v", err)
+		}
+
+		listed, err := claims.List(ctx, 0, 10, nil)
+		if err != nil || len(listed.Data) != 1 || listed.Data[0].Attributes.AccountNumber != "41426819" {
+			t.Errorf("Claims were not listed: %+v %v", listed, err)
+		}
+		if listed.Meta["count"] != float64(1) {
+			t.Errorf("Meta should be returned: %v", listed.Meta)
+		}
+
+		// Resources are kept apart by their path
+		if accounts, _ := testConfig.ListAccounts(0, 10); len(accounts) != 0 {
+			t.Errorf("Claims should not be listed as accounts: %v", accounts)
+		}
+	})
+
+	t.Run("Test Declared Resource", func(t *testing.T) {
+		type Payee struct {
+			Type       string `json:"type"`
+			ID         string `json:"id"`
+			Attributes struct {
+				Name string `json:"name"`
+			} `json:"attributes"`
+		}
+		payees := NewResource[Payee](testConfig, "/v1/confirmation-of-payee/")
+		payee := Payee{Type: "payees", ID: "1"}
+		payee.Attributes.Name = "Jane Doe"
+		if _, err := payees.Create(ctx, payee); err != nil {
+			t.Errorf("Declared resource was not created: %v", err)
+		}
+		fetched, err := payees.Fetch(ctx, "1")
+		if err != nil || fetched.Data.Attributes.Name != "Jane Doe" {
+			t.Errorf("Declared resource was not fetched: %+v %v", fetched, err)
+		}
+	})
+}
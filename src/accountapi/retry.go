@@ -0,0 +1,65 @@
+package accountapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy tells how often and how fast failed operations are retried, e.g. commands of an
+// outbox. Zero values select the defaults.
+type RetryPolicy struct {
+	// MaxAttempts including the first one, 5 by default
+	MaxAttempts int
+	// InitialInterval before the first retry, 500ms by default
+	InitialInterval time.Duration
+	// MaxInterval caps the growing interval, 1m by default
+	MaxInterval time.Duration
+	// Multiplier grows the interval after every retry, 2 by default
+	Multiplier float64
+}
+
+func (policy RetryPolicy) withDefaults() RetryPolicy {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.InitialInterval <= 0 {
+		policy.InitialInterval = 500 * time.Millisecond
+	}
+	if policy.MaxInterval <= 0 {
+		policy.MaxInterval = time.Minute
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	return policy
+}
+
+// Delay returns how long to wait after given number of failed attempts before the next one
+func (policy RetryPolicy) Delay(attempts int) time.Duration {
+	policy = policy.withDefaults()
+	delay := policy.InitialInterval
+	for i := 1; i < attempts && delay < policy.MaxInterval; i++ {
+		delay = time.Duration(float64(delay) * policy.Multiplier)
+	}
+	if delay > policy.MaxInterval {
+		delay = policy.MaxInterval
+	}
+	return delay
+}
+
+// ShouldRetry tells whether an operation that failed with err after given number of attempts
+// is attempted again
+func (policy RetryPolicy) ShouldRetry(attempts int, err error) bool {
+	return attempts < policy.withDefaults().MaxAttempts && Retryable(err)
+}
+
+// Retryable tells whether an operation that failed with err may succeed when attempted again:
+// the API was unavailable, rate limited the request, or no response was received
+func Retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return shouldFailOver(err)
+}
@@ -0,0 +1,44 @@
+package accountapi
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Second, MaxInterval: 3 * time.Second}
+
+	t.Run("Test delays grow up to the maximum", func(t *testing.T) {
+		for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 3 * time.Second, 10: 3 * time.Second} {
+			if delay := policy.Delay(attempts); delay != expected {
+				t.Errorf("Delay after %v attempts does not match: %v", attempts, delay)
+			}
+		}
+		if delay := (RetryPolicy{}).Delay(1); delay != 500*time.Millisecond {
+			t.Errorf("Default delay does not match: %v", delay)
+		}
+	})
+
+	t.Run("Test retryable errors", func(t *testing.T) {
+		for _, test := range []struct {
+			err       error
+			retryable bool
+		}{
+			{&APIError{StatusCode: http.StatusServiceUnavailable}, true},
+			{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+			{&url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")}, true},
+			{&APIError{StatusCode: http.StatusBadRequest}, false},
+			{&APIError{StatusCode: http.StatusConflict}, false},
+		} {
+			if Retryable(test.err) != test.retryable {
+				t.Errorf("Retryable of %v should be %v", test.err, test.retryable)
+			}
+		}
+		if policy.ShouldRetry(3, &APIError{StatusCode: http.StatusBadGateway}) {
+			t.Errorf("Operation should not be retried after all attempts")
+		}
+	})
+}
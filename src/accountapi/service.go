@@ -1,23 +1,44 @@
 package accountapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
 )
 
-// RequestBody wraps the Account object
-type requestBody struct {
-	Data Account `json:"data"`
+// accountPatch holds changed attributes of an account. Version is always sent,
+// as the API uses it to detect concurrent modifications.
+type accountPatch struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Version    int                    `json:"version"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
-// ResponseBody of single returned account
-type responseBody struct {
-	Data Account `json:"data"`
+// Filter narrows down listed accounts by attribute, e.g. Filter{"country": "NL"}
+// is sent as filter[country]=NL
+type Filter map[string]string
+
+// queryParams encodes the filter as query parameters appended to existing ones
+func (filter Filter) queryParams() string {
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var queryParams string
+	for _, key := range keys {
+		queryParams += "&filter[" + url.QueryEscape(key) + "]=" + url.QueryEscape(filter[key])
+	}
+	return queryParams
 }
 
-// ResponseBody of returned slice of accounts
-type sliceResponseBody struct {
-	Data []Account `json:"data"`
+// Accounts returns the client of account resources, served under AccountAPIUrl
+func (config Configuration) Accounts() Resource[Account] {
+	return Resource[Account]{config, config.AccountAPIUrl}
 }
 
 // Create method instantiates an Account object and creates an account resource via API
@@ -44,13 +65,23 @@ func (config Configuration) Create(
 	Switched bool,
 	Status string) (Account, error) {
 
+	// Timestamps are given as strings for compatibility with callers written before Timestamp
+	createdOn, err := ParseTimestamp(CreatedOn)
+	if err != nil {
+		return Account{}, err
+	}
+	modifiedOn, err := ParseTimestamp(ModifiedOn)
+	if err != nil {
+		return Account{}, err
+	}
+
 	account := Account{
 		Type,
 		ID,
 		OrganisationID,
 		Version,
-		CreatedOn,
-		ModifiedOn,
+		createdOn,
+		modifiedOn,
 		countryAttributes{
 			Country,
 			BaseCurrency,
@@ -68,6 +99,7 @@ func (config Configuration) Create(
 			Switched,
 			Status,
 		},
+		nil,
 	}
 
 	return config.CreateAccount(account)
@@ -76,106 +108,94 @@ func (config Configuration) Create(
 
 // CreateAccount creates an account resource via API from given Account object
 func (config Configuration) CreateAccount(account Account) (Account, error) {
+	return config.CreateAccountContext(context.Background(), account)
+}
 
-	var createdAccount Account
-	var createAccountResponseBody responseBody
-	var createAccountResponse []byte
-	var err error
-
-	// Create Request body
-	accountJSONReq, err := json.Marshal(requestBody{account})
-
-	// Handle Marshalling errors
-	if err != nil {
-		err := fmt.Errorf("Marshalling error: %v", err)
-		return createdAccount, err
-	}
-
-	// Create account resource
-	createAccountResponse, err = doPost(config.AccountAPIUrl, accountJSONReq)
-
-	if err != nil {
-		//log.Printf("Request failed: %v", err)
-		return createdAccount, err
-	}
-
-	// If success, unmarshal the response into desired type and return to the caller
-	err = json.Unmarshal(createAccountResponse, &createAccountResponseBody)
-
-	if err != nil {
-		//log.Printf("Unmarshalling response failed: %v", err)
-		return createdAccount, err
-	}
-
-	createdAccount = createAccountResponseBody.Data
+// CreateAccountContext creates an account resource, aborting the request when ctx is done
+func (config Configuration) CreateAccountContext(ctx context.Context, account Account) (Account, error) {
+	document, err := config.CreateAccountDocument(ctx, account)
+	return document.Data, err
+}
 
-	return createdAccount, err
+// CreateAccountDocument creates an account resource and returns the whole response document,
+// including its links and meta
+func (config Configuration) CreateAccountDocument(ctx context.Context, account Account) (Document[Account], error) {
+	return config.Accounts().Create(ctx, account)
 }
 
 // FetchAccount fetches an account resource from Account API with given Account ID
 func (config Configuration) FetchAccount(accountID string) (Account, error) {
-	var fetchedAccount Account
-	var fetchAccountResponseBody responseBody
-	var fetchAccountResponse []byte
-	var err error
-	var fetchURI = config.AccountAPIUrl + accountID
-	var queryParams = ""
+	return config.FetchAccountContext(context.Background(), accountID)
+}
 
-	// Fetch account resource
-	fetchAccountResponse, err = doGet(fetchURI, queryParams)
+// FetchAccountContext fetches an account resource, aborting the request when ctx is done
+func (config Configuration) FetchAccountContext(ctx context.Context, accountID string) (Account, error) {
+	document, err := config.FetchAccountDocument(ctx, accountID)
+	return document.Data, err
+}
 
-	if err != nil {
-		//log.Printf("Request failed: %v", err)
-		return fetchedAccount, err
-	}
+// FetchAccountDocument fetches an account resource and returns the whole response document
+func (config Configuration) FetchAccountDocument(ctx context.Context, accountID string) (Document[Account], error) {
+	return config.Accounts().Fetch(ctx, accountID)
+}
 
-	// If success, unmarshal the response into desired type and return to the caller
-	err = json.Unmarshal(fetchAccountResponse, &fetchAccountResponseBody)
+// ListAccounts fetches paged account resources
+func (config Configuration) ListAccounts(pageNumber int, pageSize int) ([]Account, error) {
+	return config.ListAccountsContext(context.Background(), pageNumber, pageSize, nil)
+}
 
-	if err != nil {
-		//log.Printf("Unmarshalling response failed: %v", err)
-		return fetchedAccount, err
-	}
+// ListAccountsContext fetches paged account resources that match given filter,
+// aborting the request when ctx is done. A nil filter matches all accounts.
+func (config Configuration) ListAccountsContext(ctx context.Context, pageNumber int, pageSize int, filter Filter) ([]Account, error) {
+	document, err := config.ListAccountsDocument(ctx, pageNumber, pageSize, filter)
+	return document.Data, err
+}
 
-	fetchedAccount = fetchAccountResponseBody.Data
+// ListAccountsDocument fetches a page of account resources and returns the whole response document.
+// Its links tell whether there is a next page, and its meta may hold counts the API sends.
+func (config Configuration) ListAccountsDocument(ctx context.Context, pageNumber int, pageSize int, filter Filter) (Document[[]Account], error) {
+	return config.Accounts().List(ctx, pageNumber, pageSize, filter)
+}
 
-	return fetchedAccount, err
+// UpdateAccount changes attributes of an existing account. Only non-empty attributes of
+// given account are sent; its ID and Version identify the account version being changed.
+func (config Configuration) UpdateAccount(account Account) (Account, error) {
+	return config.UpdateAccountContext(context.Background(), account)
 }
 
-// ListAccounts fetches paged account resources that match given filter
-func (config Configuration) ListAccounts(pageNumber int, pageSize int) ([]Account, error) {
-	var listedAccounts []Account
-	var listAccountsResponseBody sliceResponseBody
-	var listAccountsResponse []byte
-	var err error
-	var queryParams = "?page[number]=" + fmt.Sprint(pageNumber) + "&page[size]=" + fmt.Sprint(pageSize)
+// UpdateAccountContext changes attributes of an account, aborting the request when ctx is done
+func (config Configuration) UpdateAccountContext(ctx context.Context, account Account) (Account, error) {
+	document, err := config.UpdateAccountDocument(ctx, account)
+	return document.Data, err
+}
 
-	// List account resource
-	listAccountsResponse, err = doGet(config.AccountAPIUrl, queryParams)
+// UpdateAccountDocument changes attributes of an account and returns the whole response document
+func (config Configuration) UpdateAccountDocument(ctx context.Context, account Account) (Document[Account], error) {
+	patch := accountPatch{Type: account.Type, ID: account.ID, Version: account.Version}
 
-	if err != nil {
-		//log.Printf("Request failed: %v", err)
-		return listedAccounts, err
+	// Send only non-empty attributes, so that the others stay unchanged
+	bAttributes, err := json.Marshal(account.Attributes)
+	if err == nil {
+		err = json.Unmarshal(bAttributes, &patch.Attributes)
 	}
-	// If success, unmarshal the response into desired type and return to the caller
-	err = json.Unmarshal(listAccountsResponse, &listAccountsResponseBody)
-
 	if err != nil {
-		//log.Printf("Unmarshalling response failed: %v", err)
-		return listedAccounts, err
+		return Document[Account]{}, fmt.Errorf("Marshalling error: %v", err)
+	}
+	for key, value := range patch.Attributes {
+		if value == "" {
+			delete(patch.Attributes, key)
+		}
 	}
 
-	listedAccounts = listAccountsResponseBody.Data
-
-	return listedAccounts, err
+	return config.Accounts().Patch(ctx, account.ID, patch)
 }
 
 // DeleteAccount deletes account resource with given ID
 func (config Configuration) DeleteAccount(accountID string, version int) error {
-	var deleteURI = config.AccountAPIUrl + accountID
-	var queryParams = "?version=" + fmt.Sprint(version)
+	return config.DeleteAccountContext(context.Background(), accountID, version)
+}
 
-	// DELETE, when successful, does not return content.
-	_, err := doDelete(deleteURI, queryParams)
-	return err
+// DeleteAccountContext deletes account resource, aborting the request when ctx is done
+func (config Configuration) DeleteAccountContext(ctx context.Context, accountID string, version int) error {
+	return config.Accounts().Delete(ctx, accountID, version)
 }
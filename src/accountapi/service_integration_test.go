@@ -2,12 +2,42 @@ package accountapi
 
 import (
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/cassette"
 )
 
+// integrationConfig returns the configuration for given integration test. By default, Bank
+// responses are replayed from testdata/cassettes, so the tests run without the Bank's API.
+// Set AccountAPICassette=record to run against the API and record the cassettes again.
+func integrationConfig(t *testing.T) Configuration {
+	path := filepath.Join("testdata", "cassettes", t.Name()+".json")
+	transport, err := cassette.New(path, cassette.ModeFromEnv("AccountAPICassette"))
+	if err != nil {
+		t.Fatalf("Could not load cassette: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := transport.Save(); err != nil {
+			t.Errorf("Could not save cassette: %v", err)
+		}
+	})
+
+	integrationConfig, err := Load(LoadOptions{})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %v", err)
+	}
+	// Recording sends requests the way the loaded configuration does, e.g. through its proxy
+	transport.Next = integrationConfig.httpClient().Transport
+	integrationConfig.HTTPClient = &http.Client{Transport: transport, Timeout: netClient.Timeout}
+	return integrationConfig
+}
+
 func TestCreateValidUkAccount(t *testing.T) {
+	config := integrationConfig(t)
 	createdAcc, err := config.CreateAccount(validUkAccount)
 	// Validate that upon creation, create returns Account type
 	if err != nil ||
@@ -18,6 +48,7 @@ func TestCreateValidUkAccount(t *testing.T) {
 }
 
 func TestCreateValidNlAccount(t *testing.T) {
+	config := integrationConfig(t)
 	createdAcc, err := config.CreateAccount(validNlAccount)
 	// Validate that upon creation, create returns Account type
 	if err != nil ||
@@ -28,6 +59,7 @@ func TestCreateValidNlAccount(t *testing.T) {
 }
 
 func TestCreateInvalidNlAccount(t *testing.T) {
+	config := integrationConfig(t)
 	_, err := config.CreateAccount(invalidNlAccount)
 	// Validate that upon creation, create returns Account type
 	if err == nil {
@@ -36,6 +68,7 @@ func TestCreateInvalidNlAccount(t *testing.T) {
 }
 
 func TestFetchAccount(t *testing.T) {
+	config := integrationConfig(t)
 	var accountID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
 
 	fetchedAccount, err := config.FetchAccount(accountID)
@@ -49,6 +82,7 @@ func TestFetchAccount(t *testing.T) {
 }
 
 func TestListAccounts(t *testing.T) {
+	config := integrationConfig(t)
 	listedAccounts, err := config.ListAccounts(0, 10)
 	if err != nil {
 		t.Errorf("Error while fetching accounts: %v", err)
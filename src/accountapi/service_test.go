@@ -1,6 +1,7 @@
 package accountapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,7 +14,7 @@ import (
 
 func TestAccountCreationService(t *testing.T) {
 	testRouter := http.NewServeMux()
-	var testAccountResponseBody responseBody
+	var testAccountResponseBody Document[Account]
 
 	testRouter.HandleFunc("/v1/organisation/accounts/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
@@ -60,7 +61,7 @@ func TestAccountCreationService(t *testing.T) {
 	srv := httptest.NewServer(testRouter)
 	defer srv.Close()
 
-	var testConfig = Configuration{"", "", "", srv.URL + "/v1/organisation/accounts/"}
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
 
 	t.Run("Test to Create a Valid Uk Account", func(t *testing.T) {
 		createdAcc, err := testConfig.CreateAccount(validUkAccount)
@@ -139,3 +140,52 @@ func TestAccountCreationService(t *testing.T) {
 		}
 	})
 }
+
+func TestAccountUpdateService(t *testing.T) {
+	var requestedQuery string
+
+	testRouter := http.NewServeMux()
+	testRouter.HandleFunc("/v1/organisation/accounts/", func(w http.ResponseWriter, r *http.Request) {
+		requestedQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":[]}`))
+	})
+	testRouter.HandleFunc("/v1/organisation/accounts/ad27e265-9605-4b4b-a0e5-3003ea9cc4dc", func(w http.ResponseWriter, r *http.Request) {
+		bReqBody, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if r.Method != "PATCH" || string(bReqBody) != `{"data":{"type":"accounts","id":"ad27e265-9605-4b4b-a0e5-3003ea9cc4dc","version":0,"attributes":{"bic":"NWBKGB33","country":"GB"}}}` {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error_message":"unexpected request"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"attributes":{"bank_id":"400300","bank_id_code":"GBDSC","base_currency":"GBP","bic":"NWBKGB33","country":"GB"},"id":"ad27e265-9605-4b4b-a0e5-3003ea9cc4dc","organisation_id":"eb0bd6f5-c3f5-44b2-b677-acd23cdde73c","type":"accounts","version":1}}`))
+	})
+
+	srv := httptest.NewServer(testRouter)
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+	t.Run("Test to Update an Account", func(t *testing.T) {
+		var change = Account{Type: "accounts", ID: validUkAccount.ID}
+		change.Attributes.Country = "GB"
+		change.Attributes.Bic = "NWBKGB33"
+
+		updatedAcc, err := testConfig.UpdateAccount(change)
+		if err != nil || updatedAcc.Version != 1 || updatedAcc.Attributes.Bic != "NWBKGB33" {
+			t.Errorf("Error while updating account: %v %v", updatedAcc, err)
+		}
+	})
+
+	t.Run("Test to List Accounts with Filter", func(t *testing.T) {
+		_, err := testConfig.ListAccountsContext(context.Background(), 1, 5, Filter{"country": "GB", "bank_id": "400300"})
+		if err != nil {
+			t.Errorf("Error while listing accounts: %v", err)
+		}
+		if requestedQuery != "page[number]=1&page[size]=5&filter[bank_id]=400300&filter[country]=GB" {
+			t.Errorf("Unexpected query parameters: %v", requestedQuery)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package accountapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTenant is returned for organisation IDs that were not registered
+var ErrUnknownTenant = errors.New("Unknown tenant")
+
+// ErrTenantClosed is returned by a rate limited TenantClient after it was closed, e.g. because
+// the tenant was registered again. Get the current client from the registry instead.
+var ErrTenantClosed = errors.New("Tenant client is closed")
+
+// ErrTenantMismatch is returned when an account belongs to another organisation than the tenant
+var ErrTenantMismatch = errors.New("Account belongs to another organisation")
+
+// Tenant holds settings of a single organisation
+type Tenant struct {
+	// OrganisationID identifies the tenant and is enforced on its accounts
+	OrganisationID string
+	// Config points at the API used by the tenant
+	Config Configuration
+	// Authorization is sent as Authorization header of every request, e.g. "Bearer <token>"
+	Authorization string
+	// RequestsPerSecond limits requests of the tenant. Zero means no limit. Updates and deletes
+	// fetch the account to check its organisation, so they take two requests unless it is cached.
+	RequestsPerSecond float64
+	// CacheTTL keeps fetched accounts for given duration. Zero disables caching.
+	CacheTTL time.Duration
+}
+
+// TenantRegistry routes calls to per-tenant clients by organisation ID
+type TenantRegistry struct {
+	mu      sync.RWMutex
+	clients map[string]*TenantClient
+}
+
+// NewTenantRegistry returns an empty registry
+func NewTenantRegistry() *TenantRegistry {
+	return &TenantRegistry{clients: map[string]*TenantClient{}}
+}
+
+// Register adds a tenant, replacing a previously registered one with the same organisation ID.
+// The client of the replaced tenant is closed, see ErrTenantClosed.
+func (registry *TenantRegistry) Register(tenant Tenant) error {
+	if tenant.OrganisationID == "" {
+		return fmt.Errorf("Tenant must have an OrganisationID")
+	}
+	client := newTenantClient(tenant)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if previous := registry.clients[tenant.OrganisationID]; previous != nil {
+		previous.Close()
+	}
+	registry.clients[tenant.OrganisationID] = client
+	return nil
+}
+
+// Tenant returns the client of given organisation
+func (registry *TenantRegistry) Tenant(organisationID string) (*TenantClient, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	client, ok := registry.clients[organisationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTenant, organisationID)
+	}
+	return client, nil
+}
+
+// CreateAccount creates the account through the tenant of its OrganisationID
+func (registry *TenantRegistry) CreateAccount(ctx context.Context, account Account) (Account, error) {
+	client, err := registry.Tenant(account.OrganisationID)
+	if err != nil {
+		return Account{}, err
+	}
+	return client.CreateAccount(ctx, account)
+}
+
+// Close releases resources of all tenants
+func (registry *TenantRegistry) Close() {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for _, client := range registry.clients {
+		client.Close()
+	}
+}
+
+// TenantClient calls the API on behalf of a single tenant, with its own credentials,
+// rate limit and cache. Accounts of other organisations are never returned or changed.
+type TenantClient struct {
+	tenant  Tenant
+	config  Configuration
+	limiter *rateLimiter
+
+	mu    sync.Mutex
+	cache map[string]cachedAccount
+	now   func() time.Time
+}
+
+type cachedAccount struct {
+	account Account
+	expires time.Time
+}
+
+func newTenantClient(tenant Tenant) *TenantClient {
+	config := tenant.Config
+	if tenant.Authorization != "" {
+		base := config.httpClient()
+		config.HTTPClient = &http.Client{
+			Transport: authorizationTransport{tenant.Authorization, base.Transport},
+			Timeout:   base.Timeout,
+		}
+	}
+	return &TenantClient{
+		tenant:  tenant,
+		config:  config,
+		limiter: newRateLimiter(tenant.RequestsPerSecond),
+		cache:   map[string]cachedAccount{},
+		now:     time.Now,
+	}
+}
+
+// authorizationTransport adds the Authorization header to every request
+type authorizationTransport struct {
+	authorization string
+	next          http.RoundTripper
+}
+
+func (transport authorizationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := transport.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	// RoundTrippers must not modify the request, so the header is set on a copy
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", transport.authorization)
+	return next.RoundTrip(req)
+}
+
+// CreateAccount creates an account of the tenant. An empty OrganisationID is set to the tenant's one.
+func (client *TenantClient) CreateAccount(ctx context.Context, account Account) (Account, error) {
+	if account.OrganisationID == "" {
+		account.OrganisationID = client.tenant.OrganisationID
+	}
+	if account.OrganisationID != client.tenant.OrganisationID {
+		return Account{}, ErrTenantMismatch
+	}
+	if err := client.wait(ctx); err != nil {
+		return Account{}, err
+	}
+	created, err := client.config.CreateAccountContext(ctx, account)
+	if err == nil {
+		client.store(created)
+	}
+	return created, err
+}
+
+// FetchAccount fetches an account of the tenant, from cache when it is still fresh
+func (client *TenantClient) FetchAccount(ctx context.Context, accountID string) (Account, error) {
+	client.mu.Lock()
+	cached, ok := client.cache[accountID]
+	client.mu.Unlock()
+	if ok && client.now().Before(cached.expires) {
+		return cached.account, nil
+	}
+
+	if err := client.wait(ctx); err != nil {
+		return Account{}, err
+	}
+	fetched, err := client.config.FetchAccountContext(ctx, accountID)
+	if err != nil {
+		return Account{}, err
+	}
+	if fetched.OrganisationID != client.tenant.OrganisationID {
+		return Account{}, ErrTenantMismatch
+	}
+	client.store(fetched)
+	return fetched, nil
+}
+
+// ListAccounts lists a page of accounts of the tenant. The organisation is sent as filter, so
+// pages are full; accounts of other organisations that an API ignoring the filter still lists
+// are left out. A filter of another organisation fails with ErrTenantMismatch.
+func (client *TenantClient) ListAccounts(ctx context.Context, pageNumber int, pageSize int, filter Filter) ([]Account, error) {
+	if organisationID, ok := filter["organisation_id"]; ok && organisationID != client.tenant.OrganisationID {
+		return nil, ErrTenantMismatch
+	}
+	tenantFilter := Filter{"organisation_id": client.tenant.OrganisationID}
+	for key, value := range filter {
+		if key != "organisation_id" {
+			tenantFilter[key] = value
+		}
+	}
+	if err := client.wait(ctx); err != nil {
+		return nil, err
+	}
+	listed, err := client.config.ListAccountsContext(ctx, pageNumber, pageSize, tenantFilter)
+	if err != nil {
+		return nil, err
+	}
+	var owned []Account
+	for _, account := range listed {
+		if account.OrganisationID == client.tenant.OrganisationID {
+			owned = append(owned, account)
+		}
+	}
+	return owned, nil
+}
+
+// UpdateAccount changes attributes of an account of the tenant. The account is fetched first,
+// from cache when it is still fresh, to check that it belongs to the tenant.
+func (client *TenantClient) UpdateAccount(ctx context.Context, account Account) (Account, error) {
+	if account.OrganisationID != "" && account.OrganisationID != client.tenant.OrganisationID {
+		return Account{}, ErrTenantMismatch
+	}
+	if _, err := client.FetchAccount(ctx, account.ID); err != nil {
+		return Account{}, err
+	}
+	if err := client.wait(ctx); err != nil {
+		return Account{}, err
+	}
+	client.forget(account.ID)
+	updated, err := client.config.UpdateAccountContext(ctx, account)
+	if err == nil {
+		client.store(updated)
+	}
+	return updated, err
+}
+
+// DeleteAccount deletes an account of the tenant. The account is fetched first, like by UpdateAccount.
+func (client *TenantClient) DeleteAccount(ctx context.Context, accountID string, version int) error {
+	if _, err := client.FetchAccount(ctx, accountID); err != nil {
+		return err
+	}
+	if err := client.wait(ctx); err != nil {
+		return err
+	}
+	client.forget(accountID)
+	return client.config.DeleteAccountContext(ctx, accountID, version)
+}
+
+// Close releases the rate limiter of the tenant. Calls waiting for it, and later calls, fail
+// with ErrTenantClosed.
+func (client *TenantClient) Close() {
+	client.limiter.stop()
+}
+
+// wait blocks until the rate limit allows the next request
+func (client *TenantClient) wait(ctx context.Context) error {
+	err := client.limiter.wait(ctx)
+	if err == errLimiterStopped {
+		return ErrTenantClosed
+	}
+	return err
+}
+
+func (client *TenantClient) store(account Account) {
+	if client.tenant.CacheTTL <= 0 {
+		return
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.cache[account.ID] = cachedAccount{account, client.now().Add(client.tenant.CacheTTL)}
+}
+
+func (client *TenantClient) forget(accountID string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	delete(client.cache, accountID)
+}
@@ -0,0 +1,229 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const otherOrganisationID = "0c3e8c6a-2f54-4ed5-8cbb-1c3d1e0e8a55"
+
+// newTenantTestServer serves validUkAccount and an account of another organisation, listed
+// first, and only accepts requests with given Authorization header
+func newTenantTestServer(t *testing.T, authorization string, fetches *int32) *httptest.Server {
+	foreign := validNlAccount
+	foreign.OrganisationID = otherOrganisationID
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if r.Header.Get("Authorization") != authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_message":"unauthorized"}`))
+			return
+		}
+
+		switch {
+		case r.Method == "POST":
+			var body Document[Account]
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Document[Account]{Data: body.Data})
+		case r.Method == "GET" && r.URL.Path == "/v1/organisation/accounts/":
+			var listed []Account
+			for _, account := range []Account{foreign, validUkAccount} {
+				if organisationID := r.URL.Query().Get("filter[organisation_id]"); organisationID == "" || organisationID == account.OrganisationID {
+					listed = append(listed, account)
+				}
+			}
+			if size, err := strconv.Atoi(r.URL.Query().Get("page[size]")); err == nil && size < len(listed) {
+				listed = listed[:size]
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[[]Account]{Data: listed})
+		case r.Method == "GET":
+			atomic.AddInt32(fetches, 1)
+			account := validUkAccount
+			if r.URL.Path == "/v1/organisation/accounts/"+foreign.ID {
+				account = foreign
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[Account]{Data: account})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func TestTenantRegistry(t *testing.T) {
+	var fetches int32
+	srv := newTenantTestServer(t, "Bearer uk-token", &fetches)
+	defer srv.Close()
+
+	registry := NewTenantRegistry()
+	defer registry.Close()
+	err := registry.Register(Tenant{
+		OrganisationID: validUkAccount.OrganisationID,
+		Config:         Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"},
+		Authorization:  "Bearer uk-token",
+		CacheTTL:       time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Could not register tenant: %v", err)
+	}
+	registry.Register(Tenant{
+		OrganisationID: otherOrganisationID,
+		Config:         Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"},
+		Authorization:  "Bearer other-token",
+	})
+
+	t.Run("Test Routing by Organisation", func(t *testing.T) {
+		if _, err := registry.CreateAccount(context.Background(), validUkAccount); err != nil {
+			t.Errorf("Account should be created with tenant credentials: %v", err)
+		}
+
+		other := validNlAccount
+		other.OrganisationID = otherOrganisationID
+		_, err := registry.CreateAccount(context.Background(), other)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Account should be sent with credentials of other tenant, got: %v", err)
+		}
+
+		other.OrganisationID = "unregistered"
+		if _, err := registry.CreateAccount(context.Background(), other); !errors.Is(err, ErrUnknownTenant) {
+			t.Errorf("Unknown tenant should fail, got: %v", err)
+		}
+	})
+
+	client, err := registry.Tenant(validUkAccount.OrganisationID)
+	if err != nil {
+		t.Fatalf("Registered tenant not found: %v", err)
+	}
+
+	t.Run("Test Organisation Enforced", func(t *testing.T) {
+		account := validNlAccount
+		account.ID = "c5b1e5a4-46a3-4c2e-9d1c-0f6b8d1e2f3a"
+		account.OrganisationID = ""
+		created, err := client.CreateAccount(context.Background(), account)
+		if err != nil || created.OrganisationID != validUkAccount.OrganisationID {
+			t.Errorf("Tenant OrganisationID should be set: %+v %v", created, err)
+		}
+
+		account.OrganisationID = otherOrganisationID
+		if _, err := client.CreateAccount(context.Background(), account); err != ErrTenantMismatch {
+			t.Errorf("Account of other organisation should be rejected, got: %v", err)
+		}
+
+		if _, err := client.FetchAccount(context.Background(), validNlAccount.ID); err != ErrTenantMismatch {
+			t.Errorf("Fetching account of other organisation should fail, got: %v", err)
+		}
+
+		accounts, err := client.ListAccounts(context.Background(), 0, 100, nil)
+		if err != nil || len(accounts) != 1 || accounts[0].ID != validUkAccount.ID {
+			t.Errorf("Only accounts of the tenant should be listed: %v %v", accounts, err)
+		}
+
+		// The organisation is filtered by the API, so a page is not left short
+		accounts, err = client.ListAccounts(context.Background(), 0, 1, Filter{"country": "GB"})
+		if err != nil || len(accounts) != 1 || accounts[0].ID != validUkAccount.ID {
+			t.Errorf("Page of the tenant should be full: %v %v", accounts, err)
+		}
+		if _, err := client.ListAccounts(context.Background(), 0, 1, Filter{"organisation_id": otherOrganisationID}); !errors.Is(err, ErrTenantMismatch) {
+			t.Errorf("Listing accounts of other organisation should fail, got: %v", err)
+		}
+	})
+
+	t.Run("Test Cache", func(t *testing.T) {
+		client.forget(validUkAccount.ID)
+		atomic.StoreInt32(&fetches, 0)
+		for i := 0; i < 3; i++ {
+			if _, err := client.FetchAccount(context.Background(), validUkAccount.ID); err != nil {
+				t.Errorf("Fetch failed: %v", err)
+			}
+		}
+		if atomic.LoadInt32(&fetches) != 1 {
+			t.Errorf("Expected 1 fetch, got %v", fetches)
+		}
+
+		client.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		defer func() { client.now = time.Now }()
+		client.FetchAccount(context.Background(), validUkAccount.ID)
+		if atomic.LoadInt32(&fetches) != 2 {
+			t.Errorf("Expired account should be fetched again, got %v fetches", fetches)
+		}
+
+		if err := client.DeleteAccount(context.Background(), validUkAccount.ID, 0); err != nil {
+			t.Errorf("Delete failed: %v", err)
+		}
+		if _, cached := client.cache[validUkAccount.ID]; cached {
+			t.Errorf("Deleted account should be removed from cache")
+		}
+	})
+}
+
+func TestTenantRateLimit(t *testing.T) {
+	var fetches int32
+	srv := newTenantTestServer(t, "", &fetches)
+	defer srv.Close()
+
+	registry := NewTenantRegistry()
+	defer registry.Close()
+	for _, organisationID := range []string{validUkAccount.OrganisationID, otherOrganisationID} {
+		registry.Register(Tenant{
+			OrganisationID:    organisationID,
+			Config:            Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"},
+			RequestsPerSecond: 20,
+		})
+	}
+	slow, _ := registry.Tenant(validUkAccount.OrganisationID)
+	other, _ := registry.Tenant(otherOrganisationID)
+
+	// Exhausting the limit of one tenant must not slow down the other one
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		slow.ListAccounts(context.Background(), 0, 100, nil)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Rate limit was not respected, took only %v", elapsed)
+	}
+
+	start = time.Now()
+	other.ListAccounts(context.Background(), 0, 100, nil)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Other tenant should not share the rate limit, took %v", elapsed)
+	}
+
+	t.Run("Test replaced tenant", func(t *testing.T) {
+		done := make(chan error)
+		go func() {
+			// Waits for the limit, as the previous requests used it up
+			_, err := slow.ListAccounts(context.Background(), 0, 100, nil)
+			done <- err
+		}()
+		registry.Register(Tenant{
+			OrganisationID: validUkAccount.OrganisationID,
+			Config:         Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"},
+		})
+		select {
+		case err := <-done:
+			if err != nil && !errors.Is(err, ErrTenantClosed) {
+				t.Errorf("Waiting request should fail as the client is closed: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Waiting request should not block once the client is closed")
+		}
+		if _, err := slow.FetchAccount(context.Background(), validUkAccount.ID); !errors.Is(err, ErrTenantClosed) {
+			t.Errorf("Closed client should fail: %v", err)
+		}
+		current, _ := registry.Tenant(validUkAccount.OrganisationID)
+		if _, err := current.FetchAccount(context.Background(), validUkAccount.ID); err != nil {
+			t.Errorf("Registered client should be used instead: %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package accountapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimestampFormat is the millisecond precision format the API uses, e.g. "2021-01-09T13:24:54.567Z"
+const TimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// timestampLayouts are accepted when parsing, the API sends varying fractions of a second
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	// Timestamps without zone are in UTC
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Timestamp is a point in time sent by the API. It remembers the text it was parsed from,
+// so that it is marshalled back exactly as received unless its time was changed.
+type Timestamp struct {
+	time.Time
+	raw string
+	// rawTime is the time raw was parsed to, raw is only used while Time is the same instant
+	rawTime time.Time
+}
+
+// NewTimestamp returns a timestamp of given time, in UTC and with millisecond precision as the API uses
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{Time: t.UTC().Truncate(time.Millisecond)}
+}
+
+// ParseTimestamp parses a timestamp in RFC 3339 format, with any fraction of a second.
+// An empty string is the zero timestamp.
+func ParseTimestamp(value string) (Timestamp, error) {
+	if value == "" {
+		return Timestamp{}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return Timestamp{Time: parsed, raw: value, rawTime: parsed}, nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("Invalid timestamp %q, expected a format like %v", value, TimestampFormat)
+}
+
+// String returns the timestamp as received, or formatted with TimestampFormat. The zero timestamp is empty.
+func (timestamp Timestamp) String() string {
+	if timestamp.raw != "" && timestamp.rawTime.Equal(timestamp.Time) {
+		return timestamp.raw
+	}
+	if timestamp.IsZero() {
+		return ""
+	}
+	return timestamp.Time.Format(TimestampFormat)
+}
+
+// Equal reports whether both timestamps are the same instant, however they were formatted
+func (timestamp Timestamp) Equal(other Timestamp) bool {
+	return timestamp.Time.Equal(other.Time)
+}
+
+// MarshalJSON writes the timestamp as a string, see String
+func (timestamp Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timestamp.String())
+}
+
+// UnmarshalJSON reads a timestamp string. Null and empty strings are the zero timestamp.
+func (timestamp *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*timestamp = Timestamp{}
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	*timestamp = parsed
+	return nil
+}
+
+// MarshalText writes the timestamp as text, e.g. for CSV files
+func (timestamp Timestamp) MarshalText() ([]byte, error) {
+	return []byte(timestamp.String()), nil
+}
+
+// UnmarshalText reads a timestamp from text
+func (timestamp *Timestamp) UnmarshalText(text []byte) error {
+	parsed, err := ParseTimestamp(string(text))
+	if err != nil {
+		return err
+	}
+	*timestamp = parsed
+	return nil
+}
@@ -0,0 +1,63 @@
+package accountapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampRoundTrip(t *testing.T) {
+	for _, value := range []string{"2021-01-09T13:24:54.567Z", "2021-01-09T13:24:54Z", "2021-01-09T13:24:54.5Z", "2021-01-09T13:24:54.567123+01:00"} {
+		var timestamp Timestamp
+		if err := json.Unmarshal([]byte(`"`+value+`"`), &timestamp); err != nil {
+			t.Errorf("Could not parse %v: %v", value, err)
+			continue
+		}
+		bJSON, _ := json.Marshal(timestamp)
+		if string(bJSON) != `"`+value+`"` {
+			t.Errorf("Timestamp %v should round-trip exactly, got %s", value, bJSON)
+		}
+	}
+
+	parsed, _ := ParseTimestamp("2021-01-09T13:24:54.567Z")
+	if parsed.UnixMilli() != time.Date(2021, 1, 9, 13, 24, 54, 567000000, time.UTC).UnixMilli() {
+		t.Errorf("Parsed time does not match: %v", parsed.Time)
+	}
+	parsed.Time = parsed.Add(time.Second)
+	if parsed.String() != "2021-01-09T13:24:55.567Z" {
+		t.Errorf("Changed timestamp should be formatted, got %v", parsed)
+	}
+
+	if _, err := ParseTimestamp("09/01/2021"); err == nil {
+		t.Errorf("Invalid timestamp should not be parsed")
+	}
+	var empty Timestamp
+	if err := json.Unmarshal([]byte("null"), &empty); err != nil || !empty.IsZero() || empty.String() != "" {
+		t.Errorf("Null should be the zero timestamp: %v", err)
+	}
+}
+
+func TestAccountVersions(t *testing.T) {
+	older := validUkAccount
+	older.CreatedOn = NewTimestamp(time.Now().Add(-time.Hour))
+	older.ModifiedOn, _ = ParseTimestamp("2021-01-09T13:24:54.567Z")
+
+	newer := older
+	newer.ModifiedOn, _ = ParseTimestamp("2021-01-09T13:24:54.568Z")
+	if !newer.IsNewerThan(older) || older.IsNewerThan(newer) || older.IsNewerThan(older) {
+		t.Errorf("Of equal versions, the one modified later should be newer")
+	}
+
+	newer.Version = older.Version + 1
+	newer.ModifiedOn = older.ModifiedOn
+	if !newer.IsNewerThan(older) {
+		t.Errorf("Higher version should be newer")
+	}
+
+	if age := older.Age(); age < time.Hour || age > 2*time.Hour {
+		t.Errorf("Age does not match: %v", age)
+	}
+	if (Account{}).Age() != 0 {
+		t.Errorf("Account without creation time should have no age")
+	}
+}
@@ -0,0 +1,150 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// unixScheme of base URLs that reach the API over a Unix domain socket. The path of such a URL
+// is the path of the socket, a colon and the path of the API on it, e.g.
+//
+//	unix:///var/run/accountapi.sock:/v1/organisation/accounts/
+const unixScheme = "unix"
+
+// splitUnixPath splits the path of a unix:// URL into the socket path and the API path
+func splitUnixPath(path string) (socket string, apiPath string, ok bool) {
+	i := strings.Index(path, ":/")
+	if i <= 0 {
+		return "", "", false
+	}
+	return path[:i], path[i+1:], true
+}
+
+// apiURL returns the URL of given path of the API, on the host or socket of AccountAPIUrl.
+// The part of the path of AccountAPIUrl before the first segment of path is kept, so that an API
+// behind a gateway prefix is reached: with https://gw/bank/v1/organisation/accounts/, the path
+// /v1/health resolves to https://gw/bank/v1/health.
+func (config Configuration) apiURL(path string) (string, error) {
+	baseURL, err := url.Parse(config.AccountAPIUrl)
+	if err != nil {
+		return "", err
+	}
+	root := baseURL.Scheme + "://" + baseURL.Host
+	basePath := baseURL.Path
+	if baseURL.Scheme == unixScheme {
+		var socket string
+		socket, basePath, _ = splitUnixPath(baseURL.Path)
+		root = unixScheme + "://" + socket + ":"
+	}
+
+	firstSegment := "/" + strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0] + "/"
+	if i := strings.Index(basePath, firstSegment); i > 0 {
+		return root + basePath[:i] + path, nil
+	}
+	return root + path, nil
+}
+
+// unixTransport sends requests to unix:// URLs as plain HTTP over the socket named in the URL.
+// The socket path is hex encoded into the host of the request URL, so that connections to
+// different sockets are pooled apart, and decoded again when dialing.
+type unixTransport struct {
+	transport *http.Transport
+}
+
+// newUnixTransport returns a transport with the limits of given one, dialing sockets with dialer
+func newUnixTransport(limits *http.Transport, dialer *net.Dialer) unixTransport {
+	transport := limits.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		socket, err := hex.DecodeString(host)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, "unix", string(socket))
+	}
+	return unixTransport{transport}
+}
+
+func (transport unixTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	socket, path, ok := splitUnixPath(req.URL.Path)
+	if !ok {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("Invalid URL %v: socket path must be followed by a colon and the path", req.URL)
+	}
+	// RoundTrippers must not modify the request, so the URL is set on a copy
+	req = req.Clone(req.Context())
+	req.URL = &url.URL{Scheme: "http", Host: hex.EncodeToString([]byte(socket)), Path: path, RawQuery: req.URL.RawQuery}
+	req.Host = "localhost"
+	return transport.transport.RoundTrip(req)
+}
+
+// NewHTTPClient returns a client with the timeouts and limits of the shared one, that sends
+// requests through the proxy at proxyURL, or the proxies of the environment when it is empty,
+// over connections opened by dialer, or a default one when nil. Build it once and set it as
+// HTTPClient of the configurations that should share its connections, e.g.
+//
+//	client, err := accountapi.NewHTTPClient("", &net.Dialer{Timeout: time.Second, LocalAddr: addr})
+//	...
+//	config.HTTPClient = client
+func NewHTTPClient(proxyURL string, dialer *net.Dialer) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != "" {
+		parsed, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(parsed)
+	}
+	if dialer == nil {
+		dialer = defaultDialer
+	}
+	return &http.Client{Transport: newTransport(proxy, dialer), Timeout: netClient.Timeout}, nil
+}
+
+// parseProxyURL parses a proxy URL with http, https or socks5 scheme
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ProxyURL: %v", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "socks5") || parsed.Host == "" {
+		return nil, fmt.Errorf("Invalid ProxyURL %v: expected http, https or socks5 scheme and a host", parsed.Redacted())
+	}
+	return parsed, nil
+}
+
+// proxyClients are kept per ProxyURL, so that configurations with the same proxy share connections
+var proxyClients = struct {
+	sync.Mutex
+	byURL map[string]*http.Client
+}{byURL: map[string]*http.Client{}}
+
+// proxyClient returns the client sending requests through the proxy of the configuration. Requests
+// of a configuration with an invalid ProxyURL fail, which Validate reports beforehand.
+func (config Configuration) proxyClient() *http.Client {
+	proxyClients.Lock()
+	defer proxyClients.Unlock()
+	if client, ok := proxyClients.byURL[config.ProxyURL]; ok {
+		return client
+	}
+	client, err := NewHTTPClient(config.ProxyURL, nil)
+	if err != nil {
+		client = &http.Client{Transport: newTransport(func(*http.Request) (*url.URL, error) {
+			return nil, err
+		}, defaultDialer)}
+	}
+	proxyClients.byURL[config.ProxyURL] = client
+	return client
+}
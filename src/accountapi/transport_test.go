@@ -0,0 +1,198 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// accountHandler serves validUkAccount for fetches and lists, and records the requests it received
+type accountHandler struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (handler *accountHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.mu.Lock()
+	handler.requests = append(handler.requests, r)
+	handler.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	switch {
+	case r.URL.Path == HealthPath:
+		w.Write([]byte(`{"status":"up"}`))
+	case strings.HasSuffix(r.URL.Path, "/"):
+		json.NewEncoder(w).Encode(Document[[]Account]{Data: []Account{validUkAccount}})
+	default:
+		json.NewEncoder(w).Encode(Document[Account]{Data: validUkAccount})
+	}
+}
+
+func (handler *accountHandler) last() *http.Request {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	return handler.requests[len(handler.requests)-1]
+}
+
+func TestProxy(t *testing.T) {
+	// The proxy answers requests itself instead of forwarding them to the unreachable bank
+	proxy := &accountHandler{}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+
+	config := Configuration{
+		AccountAPIUrl: "http://bank.invalid/v1/organisation/accounts/",
+		ProxyURL:      strings.Replace(proxyServer.URL, "http://", "http://jane:secret@", 1),
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Configuration should be valid: %v", err)
+	}
+	account, err := config.FetchAccountContext(context.Background(), validUkAccount.ID)
+	if err != nil || account.ID != validUkAccount.ID {
+		t.Fatalf("Fetch through the proxy failed: %v", err)
+	}
+
+	request := proxy.last()
+	if request.URL.String() != "http://bank.invalid/v1/organisation/accounts/"+validUkAccount.ID {
+		t.Errorf("Proxy should receive the absolute URL: %v", request.URL)
+	}
+	credentials := "Basic " + base64.StdEncoding.EncodeToString([]byte("jane:secret"))
+	if request.Header.Get("Proxy-Authorization") != credentials {
+		t.Errorf("Proxy credentials do not match: %q", request.Header.Get("Proxy-Authorization"))
+	}
+
+	if config.httpClient() != config.httpClient() || config.httpClient() == netClient {
+		t.Errorf("Configurations with the same proxy should share their client")
+	}
+}
+
+func TestDialer(t *testing.T) {
+	server := httptest.NewServer(&accountHandler{})
+	defer server.Close()
+
+	var dials int32
+	client, err := NewHTTPClient("", &net.Dialer{Control: func(network string, address string, c syscall.RawConn) error {
+		atomic.AddInt32(&dials, 1)
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("Could not create client: %v", err)
+	}
+	config := Configuration{AccountAPIUrl: server.URL + "/v1/organisation/accounts/", HTTPClient: client}
+	for i := 0; i < 3; i++ {
+		if _, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if count := atomic.LoadInt32(&dials); count != 1 {
+		t.Errorf("Connection should be opened by the dialer once and reused: %v dials", count)
+	}
+
+	if _, err := NewHTTPClient("proxy.internal:3128", nil); err == nil {
+		t.Errorf("Invalid proxy URL should fail")
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix domain sockets are not available: %v", err)
+	}
+	handler := &accountHandler{}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	defer server.Close()
+
+	config := Configuration{AccountAPIUrl: "unix://" + socket + ":/v1/organisation/accounts/"}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Configuration should be valid: %v", err)
+	}
+
+	t.Run("Test Fetch and List", func(t *testing.T) {
+		if account, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err != nil || account.ID != validUkAccount.ID {
+			t.Fatalf("Fetch over the socket failed: %v", err)
+		}
+		if request := handler.last(); request.URL.Path != "/v1/organisation/accounts/"+validUkAccount.ID || request.Host != "localhost" {
+			t.Errorf("Request does not match: %v %v", request.Host, request.URL)
+		}
+
+		accounts, err := config.ListAccountsContext(context.Background(), 0, 10, Filter{"country": "GB"})
+		if err != nil || len(accounts) != 1 {
+			t.Fatalf("List over the socket failed: %v", err)
+		}
+		if query := handler.last().URL.Query(); query.Get("filter[country]") != "GB" || query.Get("page[size]") != "10" {
+			t.Errorf("Query does not match: %v", query)
+		}
+	})
+
+	t.Run("Test Health Check", func(t *testing.T) {
+		report := config.HealthCheck(context.Background())
+		if !report.Healthy || report.Check != "health" || report.Endpoint != "unix://"+socket+":"+HealthPath {
+			t.Errorf("Health report does not match: %+v", report)
+		}
+	})
+
+	t.Run("Test Load", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("AccountAPIProtocol", "unix://")
+		t.Setenv("AccountAPISocket", socket)
+		loaded, err := Load(LoadOptions{})
+		if err != nil || loaded.AccountAPIUrl != config.AccountAPIUrl {
+			t.Errorf("Loaded URL does not match: %v %v", loaded.AccountAPIUrl, err)
+		}
+	})
+}
+
+func TestGatewayPrefix(t *testing.T) {
+	handler := &accountHandler{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	config := Configuration{AccountAPIUrl: server.URL + "/bank/v1/organisation/accounts/"}
+
+	if _, err := config.FetchAccountContext(context.Background(), validUkAccount.ID); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if path := handler.last().URL.Path; path != "/bank/v1/organisation/accounts/"+validUkAccount.ID {
+		t.Errorf("Account path does not match: %v", path)
+	}
+	config.Organisations().List(context.Background(), 0, 10, nil)
+	if path := handler.last().URL.Path; path != "/bank"+OrganisationsPath {
+		t.Errorf("Organisations should be served below the gateway prefix: %v", path)
+	}
+	if report := config.HealthCheck(context.Background()); report.Endpoint != server.URL+"/bank"+HealthPath {
+		t.Errorf("Health endpoint should be below the gateway prefix: %v", report.Endpoint)
+	}
+
+	unix := Configuration{AccountAPIUrl: "unix:///var/run/api.sock:/bank/v1/organisation/accounts/"}
+	if url := unix.Claims().URL(); url != "unix:///var/run/api.sock:/bank"+ClaimsPath {
+		t.Errorf("Claims URL does not match: %v", url)
+	}
+	custom := Configuration{AccountAPIUrl: "https://bank.example/accounts/"}
+	if url := custom.Claims().URL(); url != "https://bank.example"+ClaimsPath {
+		t.Errorf("Paths should be resolved on the host without a matching prefix: %v", url)
+	}
+}
+
+func TestValidateTransport(t *testing.T) {
+	for _, config := range []Configuration{
+		{AccountAPIUrl: "unix:///var/run/api.sock"},
+		{AccountAPIUrl: "unix:///var/run/api.sock:/v1/organisation/accounts"},
+		{AccountAPIUrl: "unix://host/var/run/api.sock:/v1/organisation/accounts/"},
+		{AccountAPIUrl: "http://localhost:8080/v1/organisation/accounts/", ProxyURL: "proxy.internal:3128"},
+		{AccountAPIUrl: "http://localhost:8080/v1/organisation/accounts/", ProxyURL: "ftp://proxy.internal"},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Configuration should be invalid: %+v", config)
+		}
+	}
+}
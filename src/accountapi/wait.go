@@ -0,0 +1,132 @@
+package accountapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTerminalStatuses are statuses an account does not leave once reached
+var DefaultTerminalStatuses = []string{"failed", "closed"}
+
+// ErrTerminalStatus is wrapped by a WaitError when the account settled in a status that was not awaited
+var ErrTerminalStatus = errors.New("Account reached a terminal status")
+
+// WaitOptions configure WaitForAccountStatus. Zero values select the defaults.
+type WaitOptions struct {
+	// InitialInterval between the first fetches, 500ms by default
+	InitialInterval time.Duration
+	// MaxInterval caps the growing interval, 10s by default
+	MaxInterval time.Duration
+	// Multiplier grows the interval after every fetch, 2 by default
+	Multiplier float64
+	// Timeout stops waiting after given duration. Without it, waiting stops only when ctx is done.
+	Timeout time.Duration
+	// TerminalStatuses override DefaultTerminalStatuses
+	TerminalStatuses []string
+	// WaitForCreation fetches again while the account is not found, e.g. right after creating it
+	// on an API that makes accounts visible later. Otherwise not found stops waiting.
+	WaitForCreation bool
+}
+
+// WaitError is returned when the account did not reach an awaited status. Account is the
+// last observed state, empty when the account was never fetched.
+type WaitError struct {
+	AccountID string
+	Account   Account
+	// Err is ErrTerminalStatus, the error of ctx, or the fetch error that stopped waiting
+	Err error
+}
+
+func (err *WaitError) Error() string {
+	if err.Account.ID == "" {
+		return fmt.Sprintf("Waiting for account %v failed: %v", err.AccountID, err.Err)
+	}
+	return fmt.Sprintf("Waiting for account %v failed: %v, last status %q", err.AccountID, err.Err, err.Account.Attributes.Status)
+}
+
+func (err *WaitError) Unwrap() error {
+	return err.Err
+}
+
+// WaitForAccountStatus fetches the account, with growing intervals, until its status is one of
+// targetStatuses and returns it. Waiting stops with a WaitError when the account reaches a terminal
+// status, the timeout passes or ctx is done. Failures of the API or the network are fetched again,
+// as are accounts that are not found yet with WaitForCreation; other errors stop waiting.
+func (config Configuration) WaitForAccountStatus(ctx context.Context, accountID string, targetStatuses []string, options WaitOptions) (Account, error) {
+	options = options.withDefaults()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	var last Account
+	interval := options.InitialInterval
+	for {
+		account, err := config.FetchAccountContext(ctx, accountID)
+		switch {
+		case ctx.Err() != nil:
+			return last, &WaitError{accountID, last, ctx.Err()}
+		case err != nil && !options.retryableFetch(err, last):
+			return last, &WaitError{accountID, last, err}
+		case err == nil:
+			last = account
+			if containsStatus(targetStatuses, account.Attributes.Status) {
+				return account, nil
+			}
+			if containsStatus(options.TerminalStatuses, account.Attributes.Status) {
+				return account, &WaitError{accountID, account, ErrTerminalStatus}
+			}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, &WaitError{accountID, last, ctx.Err()}
+		case <-timer.C:
+		}
+		interval = time.Duration(float64(interval) * options.Multiplier)
+		if interval > options.MaxInterval {
+			interval = options.MaxInterval
+		}
+	}
+}
+
+func (options WaitOptions) withDefaults() WaitOptions {
+	if options.InitialInterval <= 0 {
+		options.InitialInterval = 500 * time.Millisecond
+	}
+	if options.MaxInterval <= 0 {
+		options.MaxInterval = 10 * time.Second
+	}
+	if options.Multiplier < 1 {
+		options.Multiplier = 2
+	}
+	if options.TerminalStatuses == nil {
+		options.TerminalStatuses = DefaultTerminalStatuses
+	}
+	return options
+}
+
+// retryableFetch tells whether fetching again may succeed: the API may be temporarily unavailable,
+// or an awaited account that was never fetched may not be visible yet. An account that was
+// fetched before and is not found anymore was deleted.
+func (options WaitOptions) retryableFetch(err error, last Account) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsNotFound() {
+		return options.WaitForCreation && last.ID == ""
+	}
+	return shouldFailOver(err)
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, candidate := range statuses {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatusTestServer answers fetches with given statuses in turn, repeating the last one.
+// An empty status answers 404, as if the account was not visible yet.
+func newStatusTestServer(fetches *int32, statuses ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(fetches, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		switch statuses[i] {
+		case "":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_message":"record does not exist"}`))
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			account := validUkAccount
+			account.Attributes.Status = statuses[i]
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(Document[Account]{Data: account})
+		}
+	}))
+}
+
+func TestWaitForAccountStatus(t *testing.T) {
+	options := WaitOptions{InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond}
+	confirmed := []string{"confirmed"}
+
+	t.Run("Test Status Reached", func(t *testing.T) {
+		var fetches int32
+		srv := newStatusTestServer(&fetches, "", "unavailable", "pending", "pending", "confirmed")
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		creationOptions := options
+		creationOptions.WaitForCreation = true
+		account, err := testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, creationOptions)
+		if fetches := atomic.LoadInt32(&fetches); err != nil || account.Attributes.Status != "confirmed" || fetches != 5 {
+			t.Errorf("Account should be confirmed after 5 fetches: %v %v %v", account.Attributes.Status, fetches, err)
+		}
+	})
+
+	t.Run("Test Terminal Status", func(t *testing.T) {
+		var fetches int32
+		srv := newStatusTestServer(&fetches, "pending", "failed")
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		account, err := testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, options)
+		var waitErr *WaitError
+		if !errors.Is(err, ErrTerminalStatus) || !errors.As(err, &waitErr) || waitErr.Account.Attributes.Status != "failed" {
+			t.Errorf("Failed account should stop waiting: %v", err)
+		}
+		if account.Attributes.Status != "failed" {
+			t.Errorf("Final account should be returned: %+v", account)
+		}
+	})
+
+	t.Run("Test Timeout", func(t *testing.T) {
+		var fetches int32
+		srv := newStatusTestServer(&fetches, "pending")
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		timeoutOptions := options
+		timeoutOptions.Timeout = 50 * time.Millisecond
+		start := time.Now()
+		_, err := testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, timeoutOptions)
+		var waitErr *WaitError
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &waitErr) || waitErr.Account.Attributes.Status != "pending" {
+			t.Errorf("Timeout should report last observed status: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Waiting should stop at timeout, took %v", elapsed)
+		}
+		// Intervals grow up to MaxInterval, so far fewer fetches than at InitialInterval are made
+		// The handler of the fetch cancelled at timeout may still be running
+		if fetches := atomic.LoadInt32(&fetches); fetches < 3 || fetches > 30 {
+			t.Errorf("Unexpected number of fetches: %v", fetches)
+		}
+	})
+
+	t.Run("Test Permanent Error", func(t *testing.T) {
+		var fetches int32
+		srv := newStatusTestServer(&fetches, "forbidden")
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		_, err := testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, options)
+		var apiErr *APIError
+		if fetches := atomic.LoadInt32(&fetches); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || fetches != 1 {
+			t.Errorf("Forbidden fetch should stop waiting at once: %v after %v fetches", err, fetches)
+		}
+	})
+
+	t.Run("Test Not Found", func(t *testing.T) {
+		var fetches int32
+		srv := newStatusTestServer(&fetches, "")
+		defer srv.Close()
+		testConfig := Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+
+		// Without a timeout, polling a missing account would never end
+		_, err := testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, options)
+		var apiErr *APIError
+		if fetches := atomic.LoadInt32(&fetches); !errors.As(err, &apiErr) || !apiErr.IsNotFound() || fetches != 1 {
+			t.Errorf("Missing account should stop waiting at once: %v after %v fetches", err, fetches)
+		}
+
+		atomic.StoreInt32(&fetches, 0)
+		deleted := newStatusTestServer(&fetches, "pending", "")
+		defer deleted.Close()
+		testConfig = Configuration{AccountAPIUrl: deleted.URL + "/v1/organisation/accounts/"}
+		creationOptions := options
+		creationOptions.WaitForCreation = true
+		_, err = testConfig.WaitForAccountStatus(context.Background(), validUkAccount.ID, confirmed, creationOptions)
+		var waitErr *WaitError
+		if fetches := atomic.LoadInt32(&fetches); !errors.As(err, &apiErr) || !apiErr.IsNotFound() || !errors.As(err, &waitErr) ||
+			waitErr.Account.Attributes.Status != "pending" || fetches != 2 {
+			t.Errorf("Account deleted while waiting should stop waiting: %v after %v fetches", err, fetches)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// DefaultWatchInterval is how often accounts are listed when WatchOptions do not tell
+const DefaultWatchInterval = 30 * time.Second
+
+// ChangeType tells how an account changed between two polls
+type ChangeType string
+
+// Change types emitted by WatchAccounts
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// AccountChange is a change of an account seen by WatchAccounts. For deleted accounts,
+// Account is the last version that was seen.
+type AccountChange struct {
+	Type    ChangeType
+	Account Account
+}
+
+// WatchOptions configure WatchAccounts
+type WatchOptions struct {
+	// Interval between polls, DefaultWatchInterval when zero
+	Interval time.Duration
+	// PageSize of listed pages, 100 when zero
+	PageSize int
+	// Filter limits watched accounts
+	Filter Filter
+	// CheckpointFile keeps accounts seen by the last poll, so that a restarted watcher only
+	// emits changes made since. Without it, every start begins with a fresh snapshot.
+	CheckpointFile string
+	// EmitExisting emits all accounts as created on the first poll when there is no checkpoint.
+	// By default the first poll only takes the snapshot.
+	EmitExisting bool
+	// OnError is called when a poll fails. The watcher keeps polling.
+	OnError func(err error)
+}
+
+// WatchAccounts periodically lists all accounts and emits changes since the previous poll,
+// comparing accounts by ID, Version and ModifiedOn. It is meant for environments where
+// notifications can not be received. The channel is closed when ctx is done.
+//
+// The checkpoint is written after all changes of a poll were received from the channel,
+// so changes are emitted again after a restart if they were not received.
+func (config Configuration) WatchAccounts(ctx context.Context, options WatchOptions) (<-chan AccountChange, error) {
+	if options.Interval <= 0 {
+		options.Interval = DefaultWatchInterval
+	}
+	snapshot, err := readCheckpoint(options.CheckpointFile)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan AccountChange)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+
+		for {
+			if next, ok := config.poll(ctx, options, snapshot, changes); ok {
+				snapshot = next
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// poll lists accounts, emits changes against snapshot and returns the new snapshot.
+// It returns false when listing failed or ctx was done before all changes were received.
+func (config Configuration) poll(ctx context.Context, options WatchOptions, snapshot map[string]Account, changes chan<- AccountChange) (map[string]Account, bool) {
+	current := map[string]Account{}
+	it := config.IterateAccounts(ctx, options.PageSize, options.Filter)
+	for it.Next() {
+		current[it.Account().ID] = it.Account()
+	}
+	if err := it.Err(); err != nil {
+		// A partial list would report missing accounts as deleted
+		if ctx.Err() == nil && options.OnError != nil {
+			options.OnError(err)
+		}
+		return nil, false
+	}
+
+	if snapshot != nil || options.EmitExisting {
+		for _, change := range diffAccounts(snapshot, current) {
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return nil, false
+			}
+		}
+	}
+
+	if err := writeCheckpoint(options.CheckpointFile, current); err != nil && options.OnError != nil {
+		options.OnError(err)
+	}
+	return current, true
+}
+
+// diffAccounts returns changes from previous to current accounts, ordered by account ID
+func diffAccounts(previous map[string]Account, current map[string]Account) []AccountChange {
+	var changes []AccountChange
+	for id, account := range current {
+		seen, existed := previous[id]
+		switch {
+		case !existed:
+			changes = append(changes, AccountChange{ChangeCreated, account})
+		case seen.Version != account.Version || !seen.ModifiedOn.Equal(account.ModifiedOn):
+			changes = append(changes, AccountChange{ChangeUpdated, account})
+		}
+	}
+	for id, account := range previous {
+		if _, exists := current[id]; !exists {
+			changes = append(changes, AccountChange{ChangeDeleted, account})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Account.ID < changes[j].Account.ID
+	})
+	return changes
+}
+
+// readCheckpoint returns accounts stored in the file, or nil when there is no file yet
+func readCheckpoint(file string) (map[string]Account, error) {
+	if file == "" {
+		return nil, nil
+	}
+	bFile, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := map[string]Account{}
+	return snapshot, json.Unmarshal(bFile, &snapshot)
+}
+
+// writeCheckpoint replaces the file atomically, so a crash never leaves a partial checkpoint
+func writeCheckpoint(file string, snapshot map[string]Account) error {
+	if file == "" {
+		return nil
+	}
+	bFile, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file+".tmp", bFile, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
@@ -0,0 +1,166 @@
+package accountapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watchTestServer lists accounts that tests change while the watcher polls
+type watchTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	accounts map[string]Account
+	lists    int32
+	failing  bool
+}
+
+func newWatchTestServer(accounts ...Account) *watchTestServer {
+	srv := &watchTestServer{accounts: map[string]Account{}}
+	for _, account := range accounts {
+		srv.accounts[account.ID] = account
+	}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		atomic.AddInt32(&srv.lists, 1)
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if srv.failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		document := Document[[]Account]{Data: []Account{}, Links: Links{"self": r.URL.String()}}
+		for _, account := range srv.accounts {
+			document.Data = append(document.Data, account)
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(document)
+	}))
+	return srv
+}
+
+// change applies given change once the watcher listed accounts at least once more
+func (srv *watchTestServer) change(do func(accounts map[string]Account)) {
+	lists := atomic.LoadInt32(&srv.lists)
+	for atomic.LoadInt32(&srv.lists) == lists {
+		time.Sleep(time.Millisecond)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	do(srv.accounts)
+}
+
+func receiveChanges(t *testing.T, changes <-chan AccountChange, count int) []AccountChange {
+	var received []AccountChange
+	for len(received) < count {
+		select {
+		case change := <-changes:
+			received = append(received, change)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %v changes, got %v", count, received)
+		}
+	}
+	return received
+}
+
+func TestWatchAccounts(t *testing.T) {
+	srv := newWatchTestServer(validUkAccount, validNlAccount)
+	defer srv.Close()
+
+	var testConfig = Configuration{AccountAPIUrl: srv.URL + "/v1/organisation/accounts/"}
+	checkpoint := filepath.Join(t.TempDir(), "accounts.checkpoint")
+	options := WatchOptions{Interval: 10 * time.Millisecond, CheckpointFile: checkpoint}
+
+	t.Run("Test Changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		changes, err := testConfig.WatchAccounts(ctx, options)
+		if err != nil {
+			t.Fatalf("Could not watch accounts: %v", err)
+		}
+
+		created := validUkAccount
+		created.ID = "0d1f1e4c-1c1e-4a8e-9b0a-3c9f1a2b3c4d"
+		srv.change(func(accounts map[string]Account) {
+			accounts[created.ID] = created
+			updated := accounts[validUkAccount.ID]
+			updated.Version++
+			accounts[updated.ID] = updated
+			delete(accounts, validNlAccount.ID)
+		})
+
+		received := receiveChanges(t, changes, 3)
+		if received[0].Type != ChangeCreated || received[0].Account.ID != created.ID ||
+			received[1].Type != ChangeUpdated || received[1].Account.Version != 1 ||
+			received[2].Type != ChangeDeleted || received[2].Account.ID != validNlAccount.ID {
+			t.Errorf("Changes do not match: %+v", received)
+		}
+
+		cancel()
+		for range changes {
+		}
+	})
+
+	t.Run("Test Restart From Checkpoint", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changes, err := testConfig.WatchAccounts(ctx, options)
+		if err != nil {
+			t.Fatalf("Could not watch accounts: %v", err)
+		}
+
+		srv.change(func(accounts map[string]Account) {
+			updated := accounts[validUkAccount.ID]
+			updated.ModifiedOn, _ = ParseTimestamp("2026-10-19T12:00:00.000Z")
+			accounts[updated.ID] = updated
+		})
+
+		// Changes seen before the restart are not emitted again
+		received := receiveChanges(t, changes, 1)
+		if received[0].Type != ChangeUpdated || received[0].Account.ModifiedOn.IsZero() {
+			t.Errorf("Only the change after restart should be emitted: %+v", received)
+		}
+	})
+
+	t.Run("Test Failed Poll", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		errs := make(chan error, 10)
+		changes, _ := testConfig.WatchAccounts(ctx, WatchOptions{
+			Interval: 10 * time.Millisecond,
+			OnError:  func(err error) { errs <- err },
+		})
+
+		srv.change(func(accounts map[string]Account) { srv.failing = true })
+		select {
+		case <-errs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Failed poll should be reported")
+		}
+		srv.change(func(accounts map[string]Account) { srv.failing = false })
+		srv.change(func(accounts map[string]Account) {})
+
+		// Accounts of the failed poll are not reported as deleted
+		select {
+		case change := <-changes:
+			t.Errorf("No change expected, got %+v", change)
+		default:
+		}
+	})
+
+	t.Run("Test Emit Existing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changes, _ := testConfig.WatchAccounts(ctx, WatchOptions{Interval: time.Hour, EmitExisting: true})
+		for _, change := range receiveChanges(t, changes, 2) {
+			if change.Type != ChangeCreated {
+				t.Errorf("Existing accounts should be emitted as created: %+v", change)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Package webhook receives account notifications of the bank over HTTP, verifies their
+// signatures and dispatches them as typed events to registered handlers.
+//
+//	receiver := webhook.NewReceiver(webhook.HMACVerifier(secret))
+//	receiver.On(webhook.StatusChanged, func(ctx context.Context, event webhook.AccountEvent) error {
+//		log.Printf("Account %v is %v now", event.Account.ID, event.Account.Attributes.Status)
+//		return nil
+//	})
+//	http.Handle("/notifications", receiver)
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// EventType tells what happened to an account
+type EventType string
+
+// Event types sent by the bank
+const (
+	Created       EventType = "created"
+	Updated       EventType = "updated"
+	Deleted       EventType = "deleted"
+	StatusChanged EventType = "status_changed"
+)
+
+// AccountEvent is a notification about a change of an account
+type AccountEvent struct {
+	// ID identifies the notification, redeliveries of the same notification share it
+	ID   string
+	Type EventType
+	// OccurredOn is when the change happened at the bank
+	OccurredOn time.Time
+	// Account is the account after the change, or the deleted account
+	Account accountapi.Account
+	// PreviousStatus is the status before a StatusChanged event, e.g. "pending"
+	PreviousStatus string
+}
+
+// notification is the body the bank posts, e.g.
+//
+//	{
+//	  "id": "6a1f3c9e-...",
+//	  "event_type": "status_changed",
+//	  "resource_type": "accounts",
+//	  "occurred_on": "2026-10-19T12:00:00.000Z",
+//	  "previous_status": "pending",
+//	  "data": { "type": "accounts", "id": "...", "attributes": { "status": "confirmed", ... } }
+//	}
+type notification struct {
+	ID             string             `json:"id"`
+	EventType      EventType          `json:"event_type"`
+	ResourceType   string             `json:"resource_type"`
+	OccurredOn     time.Time          `json:"occurred_on"`
+	PreviousStatus string             `json:"previous_status,omitempty"`
+	Data           accountapi.Account `json:"data"`
+}
+
+// decodeEvent parses and checks a notification body
+func decodeEvent(body []byte) (AccountEvent, error) {
+	var received notification
+	if err := json.Unmarshal(body, &received); err != nil {
+		return AccountEvent{}, fmt.Errorf("invalid notification: %v", err)
+	}
+	if received.ID == "" {
+		return AccountEvent{}, fmt.Errorf("invalid notification: id is missing")
+	}
+	if received.ResourceType != "" && received.ResourceType != "accounts" {
+		return AccountEvent{}, fmt.Errorf("invalid notification: unsupported resource type %q", received.ResourceType)
+	}
+
+	event := AccountEvent{
+		ID:             received.ID,
+		Type:           received.EventType,
+		OccurredOn:     received.OccurredOn,
+		Account:        received.Data,
+		PreviousStatus: received.PreviousStatus,
+	}
+	switch event.Type {
+	case Created, Deleted, StatusChanged:
+	case Updated:
+		// Updates that changed the status are reported as status changes
+		if event.PreviousStatus != "" && event.PreviousStatus != event.Account.Attributes.Status {
+			event.Type = StatusChanged
+		}
+	default:
+		return AccountEvent{}, fmt.Errorf("invalid notification: unknown event type %q", received.EventType)
+	}
+	return event, nil
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodyBytes limits the size of accepted notifications
+const DefaultMaxBodyBytes = 1 << 20
+
+// DefaultRememberedEvents is how many delivered event IDs are remembered to drop redeliveries
+const DefaultRememberedEvents = 10000
+
+// DefaultTolerance is how far the signature timestamp of accepted notifications may be from now
+const DefaultTolerance = 5 * time.Minute
+
+// HandlerFunc handles an event. An error makes the receiver answer 500, so that the bank
+// delivers the notification again later.
+type HandlerFunc func(ctx context.Context, event AccountEvent) error
+
+// Receiver is an http.Handler accepting notifications of the bank. It verifies their signature,
+// drops notifications that were handled already and dispatches events to registered handlers.
+// Notifications signed outside the tolerance are rejected, so that a captured notification
+// can not be replayed once its event ID is no longer remembered, e.g. after a restart.
+type Receiver struct {
+	verifiers []Verifier
+	// MaxBodyBytes overrides DefaultMaxBodyBytes
+	MaxBodyBytes int64
+	// Tolerance overrides DefaultTolerance
+	Tolerance time.Duration
+	// Logger reports rejected notifications and failed handlers. Nothing is logged when nil.
+	Logger *log.Logger
+
+	mu       sync.RWMutex
+	handlers map[EventType][]HandlerFunc
+	all      []HandlerFunc
+	seen     *seenEvents
+	now      func() time.Time
+}
+
+// NewReceiver returns a receiver accepting notifications that any of the verifiers accepts.
+// More verifiers are useful while the bank rotates keys.
+func NewReceiver(verifiers ...Verifier) *Receiver {
+	return &Receiver{
+		verifiers: verifiers,
+		handlers:  map[EventType][]HandlerFunc{},
+		seen:      newSeenEvents(DefaultRememberedEvents),
+		now:       time.Now,
+	}
+}
+
+// On registers a handler of events of given type
+func (receiver *Receiver) On(eventType EventType, handler HandlerFunc) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.handlers[eventType] = append(receiver.handlers[eventType], handler)
+}
+
+// OnAny registers a handler of all events
+func (receiver *Receiver) OnAny(handler HandlerFunc) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.all = append(receiver.all, handler)
+}
+
+func (receiver *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxBodyBytes := receiver.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil || int64(len(body)) > maxBodyBytes {
+		receiver.reject(w, http.StatusRequestEntityTooLarge, "notification is too large")
+		return
+	}
+
+	if !receiver.verify(r.Header, body) {
+		receiver.reject(w, http.StatusUnauthorized, ErrInvalidSignature.Error())
+		return
+	}
+	if !receiver.recent(r.Header) {
+		receiver.reject(w, http.StatusUnauthorized, "notification timestamp is outside the tolerance")
+		return
+	}
+
+	event, err := decodeEvent(body)
+	if err != nil {
+		receiver.reject(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A redelivered notification is acknowledged without handling it again. While the first
+	// delivery is still being handled, the bank is asked to retry, as handling may yet fail.
+	switch receiver.seen.begin(event.ID) {
+	case eventHandled:
+		w.WriteHeader(http.StatusOK)
+		return
+	case eventInProgress:
+		http.Error(w, "event is being handled", http.StatusConflict)
+		return
+	}
+	err = receiver.dispatch(r.Context(), event)
+	receiver.seen.finish(event.ID, err == nil)
+	if err != nil {
+		receiver.logf("Handling event %v failed: %v", event.ID, err)
+		http.Error(w, "event could not be handled", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify reports whether any verifier accepts the body
+func (receiver *Receiver) verify(header http.Header, body []byte) bool {
+	for _, verifier := range receiver.verifiers {
+		if verifier.Verify(header, body) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recent reports whether the signature timestamp is within the tolerance of now
+func (receiver *Receiver) recent(header http.Header) bool {
+	seconds, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	tolerance := receiver.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	age := receiver.now().Sub(time.Unix(seconds, 0))
+	return age <= tolerance && age >= -tolerance
+}
+
+// dispatch calls handlers of the event type, then handlers of all events, stopping at the first error
+func (receiver *Receiver) dispatch(ctx context.Context, event AccountEvent) error {
+	receiver.mu.RLock()
+	handlers := append(append([]HandlerFunc{}, receiver.handlers[event.Type]...), receiver.all...)
+	receiver.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (receiver *Receiver) reject(w http.ResponseWriter, statusCode int, message string) {
+	receiver.logf("Rejected notification: %v", message)
+	http.Error(w, message, statusCode)
+}
+
+func (receiver *Receiver) logf(format string, args ...interface{}) {
+	if receiver.Logger != nil {
+		receiver.Logger.Printf(format, args...)
+	}
+}
+
+// seenEvents remembers IDs of the latest handled events. An event being handled is remembered
+// too, so that concurrent redeliveries are not handled twice, and forgotten again if handling fails.
+type seenEvents struct {
+	mu       sync.Mutex
+	capacity int
+	state    map[string]bool // false while being handled, true once handled
+	order    []string
+}
+
+func newSeenEvents(capacity int) *seenEvents {
+	return &seenEvents{capacity: capacity, state: map[string]bool{}}
+}
+
+// States of an event returned by begin
+const (
+	eventNew = iota
+	eventInProgress
+	eventHandled
+)
+
+// begin returns the state of the event, marking a new event as being handled
+func (seen *seenEvents) begin(id string) int {
+	seen.mu.Lock()
+	defer seen.mu.Unlock()
+	handled, known := seen.state[id]
+	switch {
+	case !known:
+		seen.state[id] = false
+		return eventNew
+	case handled:
+		return eventHandled
+	default:
+		return eventInProgress
+	}
+}
+
+// finish remembers a handled event, or forgets an event that failed so it can be delivered again
+func (seen *seenEvents) finish(id string, handled bool) {
+	seen.mu.Lock()
+	defer seen.mu.Unlock()
+	if !handled {
+		delete(seen.state, id)
+		return
+	}
+	seen.state[id] = true
+	seen.order = append(seen.order, id)
+	if len(seen.order) > seen.capacity {
+		delete(seen.state, seen.order[0])
+		seen.order = seen.order[1:]
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SignatureHeader carries the signature of a notification as "<algorithm>=<signature>",
+// e.g. "hmac-sha256=9f86d08..." or "rsa-sha256=MEUCIQ...". The bank signs the value of
+// TimestampHeader, a dot and the body, so that a captured notification can not be sent again
+// with a new timestamp.
+const SignatureHeader = "X-Signature"
+
+// TimestampHeader carries the time the notification was signed at, in seconds since the Unix epoch
+const TimestampHeader = "X-Signature-Timestamp"
+
+// ErrInvalidSignature is returned when a notification is not signed by the bank
+var ErrInvalidSignature = errors.New("Invalid notification signature")
+
+// Verifier checks that a notification, its timestamp and body, was signed by the bank
+type Verifier interface {
+	Verify(header http.Header, body []byte) error
+}
+
+// VerifierFunc adapts a function to a Verifier
+type VerifierFunc func(header http.Header, body []byte) error
+
+// Verify calls the function
+func (verify VerifierFunc) Verify(header http.Header, body []byte) error {
+	return verify(header, body)
+}
+
+// HMACVerifier accepts notifications signed with HMAC-SHA256 and given shared secret, hex encoded
+func HMACVerifier(secret []byte) Verifier {
+	return VerifierFunc(func(header http.Header, body []byte) error {
+		signature, err := signatureOf(header, "hmac-sha256")
+		if err != nil {
+			return err
+		}
+		received, err := hex.DecodeString(signature)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signedContent(header, body))
+		if !hmac.Equal(received, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// RSAVerifier accepts notifications signed with RSA PKCS #1 v1.5 over SHA-256 and the private key
+// matching given public key, base64 encoded
+func RSAVerifier(key *rsa.PublicKey) Verifier {
+	return VerifierFunc(func(header http.Header, body []byte) error {
+		signature, err := signatureOf(header, "rsa-sha256")
+		if err != nil {
+			return err
+		}
+		received, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		digest := sha256.Sum256(signedContent(header, body))
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], received) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// signedContent returns what the bank signs: the timestamp, a dot and the body
+func signedContent(header http.Header, body []byte) []byte {
+	return append([]byte(header.Get(TimestampHeader)+"."), body...)
+}
+
+// signatureOf returns the signature of given algorithm from the header
+func signatureOf(header http.Header, algorithm string) (string, error) {
+	for _, value := range header.Values(SignatureHeader) {
+		for _, part := range strings.Split(value, ",") {
+			name, signature, found := strings.Cut(strings.TrimSpace(part), "=")
+			if found && name == algorithm {
+				return signature, nil
+			}
+		}
+	}
+	return "", ErrInvalidSignature
+}
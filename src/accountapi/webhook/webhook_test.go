@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("notification-secret")
+
+const statusNotification = `{
+  "id": "6a1f3c9e-0f42-4f1b-9f57-1f5d3c2b8a11",
+  "event_type": "updated",
+  "resource_type": "accounts",
+  "occurred_on": "2026-10-19T12:00:00.000Z",
+  "previous_status": "pending",
+  "data": {"type": "accounts", "id": "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc", "organisation_id": "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c", "version": 1, "attributes": {"country": "GB", "status": "confirmed"}}
+}`
+
+// signedAt is the signature timestamp of delivered notifications
+var signedAt = strconv.FormatInt(time.Now().Unix(), 10)
+
+func hmacSignature(body string) string {
+	return hmacSignatureAt(signedAt, body)
+}
+
+func hmacSignatureAt(timestamp string, body string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + body))
+	return "hmac-sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts a notification signed at signedAt to the receiver and returns the status code
+func deliver(receiver http.Handler, body string, signature string) int {
+	return deliverAt(receiver, signedAt, body, signature)
+}
+
+func deliverAt(receiver http.Handler, timestamp string, body string, signature string) int {
+	req := httptest.NewRequest("POST", "/notifications", strings.NewReader(body))
+	if signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+	if timestamp != "" {
+		req.Header.Set(TimestampHeader, timestamp)
+	}
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestReceiver(t *testing.T) {
+	receiver := NewReceiver(HMACVerifier(secret))
+
+	var statusChanges, all []AccountEvent
+	fail := false
+	receiver.On(StatusChanged, func(ctx context.Context, event AccountEvent) error {
+		if fail {
+			return errors.New("database is down")
+		}
+		statusChanges = append(statusChanges, event)
+		return nil
+	})
+	receiver.OnAny(func(ctx context.Context, event AccountEvent) error {
+		all = append(all, event)
+		return nil
+	})
+
+	t.Run("Test Signature", func(t *testing.T) {
+		if code := deliver(receiver, statusNotification, ""); code != http.StatusUnauthorized {
+			t.Errorf("Unsigned notification should be rejected, got %v", code)
+		}
+		if code := deliver(receiver, statusNotification, hmacSignature(statusNotification+" ")); code != http.StatusUnauthorized {
+			t.Errorf("Notification with wrong signature should be rejected, got %v", code)
+		}
+		if len(all) != 0 {
+			t.Errorf("Rejected notifications should not be dispatched")
+		}
+	})
+
+	t.Run("Test Failed Handler", func(t *testing.T) {
+		fail = true
+		defer func() { fail = false }()
+		if code := deliver(receiver, statusNotification, hmacSignature(statusNotification)); code != http.StatusInternalServerError {
+			t.Errorf("Failed handler should ask for redelivery, got %v", code)
+		}
+	})
+
+	t.Run("Test Dispatch and Deduplication", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if code := deliver(receiver, statusNotification, hmacSignature(statusNotification)); code != http.StatusOK {
+				t.Errorf("Delivery %v should be accepted, got %v", i, code)
+			}
+		}
+		if len(statusChanges) != 1 {
+			t.Fatalf("Event should be handled once, got %v", len(statusChanges))
+		}
+		event := statusChanges[0]
+		if event.Type != StatusChanged || event.PreviousStatus != "pending" || event.Account.Attributes.Status != "confirmed" {
+			t.Errorf("Update of status should be a status change: %+v", event)
+		}
+		if event.OccurredOn.IsZero() || event.Account.Version != 1 {
+			t.Errorf("Event does not match: %+v", event)
+		}
+		// Dispatching the failed delivery stopped at the failing handler
+		if len(all) != 1 {
+			t.Errorf("Handler of all events should be called once, got %v", len(all))
+		}
+	})
+
+	t.Run("Test Invalid Notifications", func(t *testing.T) {
+		for _, body := range []string{`{"id":"1","event_type":"closed"}`, `{"event_type":"created"}`, `{"id":"2","event_type":"created","resource_type":"payments"}`, `[`} {
+			if code := deliver(receiver, body, hmacSignature(body)); code != http.StatusBadRequest {
+				t.Errorf("Invalid notification %v should be rejected, got %v", body, code)
+			}
+		}
+
+		receiver.MaxBodyBytes = 16
+		defer func() { receiver.MaxBodyBytes = 0 }()
+		if code := deliver(receiver, statusNotification, hmacSignature(statusNotification)); code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Large notification should be rejected, got %v", code)
+		}
+	})
+
+	t.Run("Test Method", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		receiver.ServeHTTP(recorder, httptest.NewRequest("GET", "/notifications", nil))
+		if recorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET should not be allowed, got %v", recorder.Code)
+		}
+	})
+}
+
+func TestRSAVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	rotated, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	sign := func(key *rsa.PrivateKey, body string) string {
+		digest := sha256.Sum256([]byte(signedAt + "." + body))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return "rsa-sha256=" + base64.StdEncoding.EncodeToString(signature)
+	}
+
+	// Notifications signed with either key are accepted while keys are rotated
+	receiver := NewReceiver(RSAVerifier(&key.PublicKey), RSAVerifier(&rotated.PublicKey))
+	var created []AccountEvent
+	receiver.On(Created, func(ctx context.Context, event AccountEvent) error {
+		created = append(created, event)
+		return nil
+	})
+
+	body := strings.Replace(statusNotification, `"event_type": "updated"`, `"event_type": "created"`, 1)
+	if code := deliver(receiver, body, sign(rotated, body)); code != http.StatusOK {
+		t.Errorf("Notification signed with rotated key should be accepted, got %v", code)
+	}
+	if len(created) != 1 || created[0].Type != Created {
+		t.Errorf("Created event should be dispatched: %+v", created)
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if code := deliver(receiver, body, sign(other, body)); code != http.StatusUnauthorized {
+		t.Errorf("Notification signed with unknown key should be rejected, got %v", code)
+	}
+	if code := deliver(receiver, body, hmacSignature(body)); code != http.StatusUnauthorized {
+		t.Errorf("HMAC signature should not be accepted by RSA verifier, got %v", code)
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	receiver := NewReceiver(HMACVerifier(secret))
+	now := time.Now()
+	receiver.now = func() time.Time { return now }
+	var handled int
+	receiver.OnAny(func(ctx context.Context, event AccountEvent) error {
+		handled++
+		return nil
+	})
+
+	old := strconv.FormatInt(now.Add(-DefaultTolerance-time.Minute).Unix(), 10)
+	if code := deliverAt(receiver, old, statusNotification, hmacSignatureAt(old, statusNotification)); code != http.StatusUnauthorized {
+		t.Errorf("Notification signed before the tolerance should be rejected, got %v", code)
+	}
+	future := strconv.FormatInt(now.Add(DefaultTolerance+time.Minute).Unix(), 10)
+	if code := deliverAt(receiver, future, statusNotification, hmacSignatureAt(future, statusNotification)); code != http.StatusUnauthorized {
+		t.Errorf("Notification signed after the tolerance should be rejected, got %v", code)
+	}
+	if code := deliverAt(receiver, "", statusNotification, hmacSignatureAt("", statusNotification)); code != http.StatusUnauthorized {
+		t.Errorf("Notification without timestamp should be rejected, got %v", code)
+	}
+
+	// A captured notification can not be sent again with a new timestamp
+	recent := strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+	if code := deliverAt(receiver, recent, statusNotification, hmacSignatureAt(old, statusNotification)); code != http.StatusUnauthorized {
+		t.Errorf("Notification with changed timestamp should be rejected, got %v", code)
+	}
+	if handled != 0 {
+		t.Errorf("Rejected notifications should not be dispatched")
+	}
+
+	if code := deliverAt(receiver, recent, statusNotification, hmacSignatureAt(recent, statusNotification)); code != http.StatusOK || handled != 1 {
+		t.Errorf("Notification signed within the tolerance should be accepted, got %v", code)
+	}
+	receiver.Tolerance = 30 * time.Second
+	receiver.seen = newSeenEvents(DefaultRememberedEvents)
+	if code := deliverAt(receiver, recent, statusNotification, hmacSignatureAt(recent, statusNotification)); code != http.StatusUnauthorized {
+		t.Errorf("Notification signed before the configured tolerance should be rejected, got %v", code)
+	}
+}
+
+func TestSeenEvents(t *testing.T) {
+	seen := newSeenEvents(2)
+	for _, id := range []string{"1", "2", "3"} {
+		if seen.begin(id) != eventNew {
+			t.Errorf("Event %v should be new", id)
+		}
+		if id != "3" {
+			seen.finish(id, true)
+		}
+	}
+	if seen.begin("3") != eventInProgress || seen.begin("2") != eventHandled {
+		t.Errorf("States of events do not match")
+	}
+	seen.finish("3", true)
+	// Only the latest events are remembered
+	if seen.begin("1") != eventNew {
+		t.Errorf("Oldest event should be forgotten")
+	}
+	if strings.Join(seen.order, ",") != "2,3" {
+		t.Errorf("Remembered events do not match: %v", seen.order)
+	}
+}
@@ -0,0 +1,136 @@
+// Command accountapi-mock serves a fake of the bank's Account API, so that integration
+// tests can run without the real bank.
+//
+// It listens on AccountAPISocket and serves accounts under AccountAPIUri, the same
+// environment variables the accountapi library reads. A socket that is a path, like
+// /tmp/accountapi.sock, is a Unix domain socket, reached with AccountAPIProtocol=unix://. Accounts are kept in memory,
+// or in a JSON file when -data is given, and can be seeded from a JSON file with -seed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+)
+
+func main() {
+	dataFile := flag.String("data", "", "JSON file to persist accounts to; accounts are kept in memory only when empty")
+	seedFile := flag.String("seed", "", "JSON file with accounts to load on startup")
+	flag.Parse()
+
+	socket := getenv("AccountAPISocket", "localhost:8080")
+	uri := getenv("AccountAPIUri", accountapitest.DefaultURI)
+
+	handler := accountapitest.NewHandler(uri)
+
+	for _, file := range []string{*dataFile, *seedFile} {
+		if file == "" {
+			continue
+		}
+		accounts, err := loadAccounts(file)
+		if err != nil && !(file == *dataFile && os.IsNotExist(err)) {
+			log.Fatalf("Could not load accounts from %v: %v", file, err)
+		}
+		handler.Seed(accounts...)
+	}
+
+	log.Printf("Serving mock Account API on %v%v", socket, uri)
+	listener, err := listen(socket)
+	if err != nil {
+		log.Fatalf("Could not listen on %v: %v", socket, err)
+	}
+	log.Fatal(http.Serve(listener, newMux(handler, *dataFile)))
+}
+
+// listen listens on a Unix domain socket when socket is a path, replacing one left by an earlier
+// run, or on a TCP address otherwise
+func listen(socket string) (net.Listener, error) {
+	if !strings.HasPrefix(socket, "/") {
+		return net.Listen("tcp", socket)
+	}
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socket)
+	}
+	return net.Listen("unix", socket)
+}
+
+// newMux serves the accounts handler next to /health and /v1/health endpoints. When dataFile is set,
+// accounts are written to it after every request that may have changed them.
+func newMux(handler *accountapitest.Handler, dataFile string) *http.ServeMux {
+	mux := http.NewServeMux()
+	// saving serialises writes of dataFile. Accounts are read once it is held, so the last write
+	// holds all changes made by requests that finished before it.
+	var saving sync.Mutex
+
+	health := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"up"}`))
+	}
+	// The Account API answers /v1/health, /health is kept for existing probes
+	mux.HandleFunc("/health", health)
+	mux.HandleFunc("/v1/health", health)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		if dataFile != "" && r.Method != "GET" {
+			saving.Lock()
+			defer saving.Unlock()
+			if err := saveAccounts(dataFile, handler.Accounts()); err != nil {
+				log.Printf("Could not persist accounts to %v: %v", dataFile, err)
+			}
+		}
+	})
+
+	return mux
+}
+
+// loadAccounts reads accounts from a JSON array, or from a {"data": [...]} document
+// as returned by the list endpoint
+func loadAccounts(file string) ([]accountapi.Account, error) {
+	bFile, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []accountapi.Account
+	if err = json.Unmarshal(bFile, &accounts); err == nil {
+		return accounts, nil
+	}
+
+	var document struct {
+		Data []accountapi.Account `json:"data"`
+	}
+	if err = json.Unmarshal(bFile, &document); err != nil {
+		return nil, err
+	}
+	return document.Data, nil
+}
+
+// saveAccounts writes accounts to a temporary file first, so that a crash never leaves a partial file
+func saveAccounts(file string, accounts []accountapi.Account) error {
+	bFile, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file+".tmp", bFile, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// getenv returns value of environment variable, or given default when it is not set
+func getenv(key string, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+)
+
+func TestHealth(t *testing.T) {
+	srv := httptest.NewServer(newMux(accountapitest.NewHandler(accountapitest.DefaultURI), ""))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Health check failed: %v %v", resp, err)
+	}
+	bResponseBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(bResponseBody) != `{"status":"up"}` {
+		t.Errorf("Unexpected health response: %v", string(bResponseBody))
+	}
+
+	config := accountapi.Configuration{AccountAPIUrl: srv.URL + accountapitest.DefaultURI}
+	if report := config.HealthCheck(context.Background()); !report.Healthy || report.Check != "health" {
+		t.Errorf("Health check of the library should use the health endpoint: %+v", report)
+	}
+}
+
+func TestFilePersistence(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "accounts.json")
+	account := accountapi.Account{Type: "accounts", ID: "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc", OrganisationID: "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"}
+	account.Attributes.Country = "GB"
+
+	srv := httptest.NewServer(newMux(accountapitest.NewHandler(accountapitest.DefaultURI), dataFile))
+	config := accountapi.Configuration{AccountAPIUrl: srv.URL + accountapitest.DefaultURI}
+	if _, err := config.CreateAccount(account); err != nil {
+		t.Fatalf("Error while creating account: %v", err)
+	}
+	srv.Close()
+
+	// A fresh server loading the data file should serve the created account
+	accounts, err := loadAccounts(dataFile)
+	if err != nil || len(accounts) != 1 {
+		t.Fatalf("Persisted accounts do not match: %v %v", accounts, err)
+	}
+	handler := accountapitest.NewHandler(accountapitest.DefaultURI)
+	handler.Seed(accounts...)
+	srv = httptest.NewServer(newMux(handler, dataFile))
+	defer srv.Close()
+
+	config = accountapi.Configuration{AccountAPIUrl: srv.URL + accountapitest.DefaultURI}
+	fetched, err := config.FetchAccount(account.ID)
+	if err != nil || fetched.ID != account.ID {
+		t.Errorf("Persisted account was not served: %v %v", fetched, err)
+	}
+}
+
+func TestConcurrentPersistence(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "accounts.json")
+	srv := httptest.NewServer(newMux(accountapitest.NewHandler(accountapitest.DefaultURI), dataFile))
+	defer srv.Close()
+	config := accountapi.Configuration{AccountAPIUrl: srv.URL + accountapitest.DefaultURI}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			account := accountapi.Account{Type: "accounts", ID: fmt.Sprintf("ad27e265-9605-4b4b-a0e5-3003ea9cc4%02d", i), OrganisationID: "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"}
+			account.Attributes.Country = "GB"
+			if _, err := config.CreateAccount(account); err != nil {
+				t.Errorf("Error while creating account: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if accounts, err := loadAccounts(dataFile); err != nil || len(accounts) != 20 {
+		t.Errorf("All created accounts should be persisted, got %v: %v", len(accounts), err)
+	}
+}
+
+func TestLoadListDocument(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "seed.json")
+	ioutil.WriteFile(seedFile, []byte(`{"data":[{"type":"accounts","id":"bf33e333-9605-4b4b-a0e5-3003ea9cc4dc","attributes":{"country":"NL"}}]}`), 0644)
+
+	accounts, err := loadAccounts(seedFile)
+	if err != nil || len(accounts) != 1 || accounts[0].Attributes.Country != "NL" {
+		t.Errorf("Seed document was not loaded: %v %v", accounts, err)
+	}
+}
@@ -0,0 +1,390 @@
+// Command accountctl manages bank accounts through the accountapi library.
+//
+// Usage:
+//
+//	accountctl create [-file account.json | attribute flags]
+//	accountctl get ID
+//	accountctl list [-page N] [-size N] [-filter key=value ...]
+//	accountctl update ID [-version N] attribute flags
+//	accountctl delete ID [-version N]
+//	accountctl plan -file accounts.yaml [-filter key=value ...] [-ignore field,...] [-allow-empty]
+//	accountctl apply -file accounts.yaml [-filter key=value ...] [-ignore field,...] [-allow-empty] [-dry-run] [-confirm]
+//
+// Plan compares the desired accounts of the file with the listed ones and prints the accounts
+// to create, update and delete; apply makes these changes. Listed accounts missing from the
+// file are deleted, so -filter should select the accounts the file manages. Without -filter,
+// apply only deletes accounts with -confirm, and a file without accounts needs -allow-empty.
+//
+// Every command reads configuration like the library does, from environment variables and
+// the file and profile given with -config and -profile. The -url flag points at another API
+// and -o chooses table, json or yaml output.
+// Exit codes tell apart usage errors (2), missing accounts (3), version conflicts (4),
+// rejected requests (5), server errors (6) and an unreachable API (7).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+)
+
+// Exit codes
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitConflict    = 4
+	exitInvalid     = 5
+	exitServerError = 6
+	exitUnavailable = 7
+)
+
+// errUsage marks errors in command line arguments
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// command runs a subcommand with its own flags, writing results to stdout
+type command func(args []string, stdout io.Writer) error
+
+var commands = map[string]command{
+	"create": createCommand,
+	"get":    getCommand,
+	"list":   listCommand,
+	"update": updateCommand,
+	"delete": deleteCommand,
+	"plan":   planCommand,
+	"apply":  applyCommand,
+}
+
+// run executes the command line and returns the exit code
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || commands[args[0]] == nil {
+		fmt.Fprintln(stderr, "Usage: accountctl create|get|list|update|delete|plan|apply [flags]")
+		return exitUsage
+	}
+
+	err := commands[args[0]](args[1:], stdout)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "accountctl %v: %v\n", args[0], err)
+		}
+	}
+	return exitCode(err)
+}
+
+// exitCode maps errors to exit codes, using the status code of API errors
+func exitCode(err error) int {
+	var apiErr *accountapi.APIError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.IsNotFound():
+			return exitNotFound
+		case apiErr.IsConflict():
+			return exitConflict
+		case apiErr.StatusCode >= 500:
+			return exitServerError
+		case apiErr.StatusCode >= 400:
+			return exitInvalid
+		}
+		return exitError
+	}
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) {
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// commonFlags are accepted by every command
+type commonFlags struct {
+	file    string
+	profile string
+	url     string
+	output  string
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	common := &commonFlags{}
+	flags.StringVar(&common.file, "config", "", "YAML, JSON or TOML configuration file")
+	flags.StringVar(&common.profile, "profile", "", "configuration profile, e.g. dev or prod")
+	flags.StringVar(&common.url, "url", "", "Account API URL, overriding the configuration")
+	flags.StringVar(&common.output, "o", formatTable, "output format: table, json or yaml")
+	return flags, common
+}
+
+// parse parses flags and returns positional arguments, of which exactly want are required
+func parse(flags *flag.FlagSet, args []string, want int) ([]string, error) {
+	// Allow flags after positional arguments, e.g. "get ID -o json"
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) != want {
+		return nil, fmt.Errorf("%w: expected %v arguments, got %v", errUsage, want, len(positional))
+	}
+	return positional, nil
+}
+
+// config loads the configuration from file and environment, as the library does
+func (common *commonFlags) config() (accountapi.Configuration, error) {
+	config, err := accountapi.Load(accountapi.LoadOptions{File: common.file, Profile: common.profile})
+	if err != nil || common.url == "" {
+		return config, err
+	}
+	config.AccountAPIUrl = common.url
+	if !strings.HasSuffix(config.AccountAPIUrl, "/") {
+		config.AccountAPIUrl = config.AccountAPIUrl + "/"
+	}
+	return config, config.Validate()
+}
+
+// attributeFlags set account attributes from the command line. Empty flags leave attributes unchanged.
+type attributeFlags struct {
+	organisationID, country, baseCurrency, accountNumber, bankID, bankIDCode string
+	bic, iban, names, alternativeNames, classification, secondaryID, status  string
+}
+
+func (attributes *attributeFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&attributes.organisationID, "organisation-id", "", "organisation ID")
+	flags.StringVar(&attributes.country, "country", "", "ISO country code")
+	flags.StringVar(&attributes.baseCurrency, "base-currency", "", "ISO currency code")
+	flags.StringVar(&attributes.accountNumber, "account-number", "", "account number")
+	flags.StringVar(&attributes.bankID, "bank-id", "", "bank ID")
+	flags.StringVar(&attributes.bankIDCode, "bank-id-code", "", "bank ID code")
+	flags.StringVar(&attributes.bic, "bic", "", "BIC")
+	flags.StringVar(&attributes.iban, "iban", "", "IBAN")
+	flags.StringVar(&attributes.names, "name", "", "comma separated account holder names")
+	flags.StringVar(&attributes.alternativeNames, "alternative-names", "", "comma separated alternative names")
+	flags.StringVar(&attributes.classification, "classification", "", "Personal or Business")
+	flags.StringVar(&attributes.secondaryID, "secondary-identification", "", "secondary identification")
+	flags.StringVar(&attributes.status, "status", "", "account status")
+}
+
+func (attributes *attributeFlags) apply(account *accountapi.Account) {
+	set := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+	set(&account.OrganisationID, attributes.organisationID)
+	set(&account.Attributes.Country, attributes.country)
+	set(&account.Attributes.BaseCurrency, attributes.baseCurrency)
+	set(&account.Attributes.AccountNumber, attributes.accountNumber)
+	set(&account.Attributes.BankID, attributes.bankID)
+	set(&account.Attributes.BankIDCode, attributes.bankIDCode)
+	set(&account.Attributes.Bic, attributes.bic)
+	set(&account.Attributes.Iban, attributes.iban)
+	set(&account.Attributes.AccountClassification, attributes.classification)
+	set(&account.Attributes.SecondaryIdentification, attributes.secondaryID)
+	set(&account.Attributes.Status, attributes.status)
+	if attributes.names != "" {
+		account.Attributes.Name = strings.Split(attributes.names, ",")
+	}
+	if attributes.alternativeNames != "" {
+		account.Attributes.AlternativeNames = strings.Split(attributes.alternativeNames, ",")
+	}
+}
+
+func createCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("create")
+	file := flags.String("file", "", "JSON file with the account, either bare or wrapped in \"data\"")
+	id := flags.String("id", "", "account ID")
+	var attributes attributeFlags
+	attributes.register(flags)
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	account := accountapi.Account{Type: "accounts"}
+	if *file != "" {
+		var err error
+		if account, err = readAccountFile(*file); err != nil {
+			return err
+		}
+	}
+	if *id != "" {
+		account.ID = *id
+	}
+	attributes.apply(&account)
+
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+	created, err := config.CreateAccount(account)
+	if err != nil {
+		return err
+	}
+	return writeAccounts(stdout, common.output, []accountapi.Account{created})
+}
+
+// readAccountFile reads an account from a JSON file holding either the account or a {"data": account} document
+func readAccountFile(file string) (accountapi.Account, error) {
+	var document struct {
+		Data *accountapi.Account `json:"data"`
+	}
+	var account accountapi.Account
+
+	bFile, err := ioutil.ReadFile(file)
+	if err != nil {
+		return account, err
+	}
+	if err = json.Unmarshal(bFile, &document); err == nil && document.Data != nil {
+		account = *document.Data
+	} else if err = json.Unmarshal(bFile, &account); err != nil {
+		return account, fmt.Errorf("Invalid account file %v: %v", file, err)
+	}
+	if account.Type == "" {
+		account.Type = "accounts"
+	}
+	return account, nil
+}
+
+func getCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("get")
+	positional, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+	account, err := config.FetchAccount(positional[0])
+	if err != nil {
+		return err
+	}
+	return writeAccounts(stdout, common.output, []accountapi.Account{account})
+}
+
+// filterFlag collects repeated -filter key=value flags
+type filterFlag accountapi.Filter
+
+func (filter filterFlag) String() string {
+	return fmt.Sprint(map[string]string(filter))
+}
+
+func (filter filterFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("filter %q is not in key=value format", value)
+	}
+	filter[parts[0]] = parts[1]
+	return nil
+}
+
+func listCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("list")
+	page := flags.Int("page", 0, "page number")
+	size := flags.Int("size", 100, "page size")
+	filter := filterFlag{}
+	flags.Var(filter, "filter", "attribute filter in key=value format, can be repeated")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+	accounts, err := config.ListAccountsContext(context.Background(), *page, *size, accountapi.Filter(filter))
+	if err != nil {
+		return err
+	}
+	if accounts == nil {
+		accounts = []accountapi.Account{}
+	}
+	return writeAccounts(stdout, common.output, accounts)
+}
+
+func updateCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("update")
+	version := flags.Int("version", accountapi.UnknownVersion, "current version, fetched when not given")
+	var attributes attributeFlags
+	attributes.register(flags)
+	positional, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	// Updates only send attributes, an account can not move to another organisation
+	if attributes.organisationID != "" {
+		return fmt.Errorf("%w: -organisation-id can not be changed by an update", errUsage)
+	}
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+
+	account := accountapi.Account{Type: "accounts", ID: positional[0], Version: *version}
+	if account.Version == accountapi.UnknownVersion {
+		current, err := config.FetchAccount(account.ID)
+		if err != nil {
+			return err
+		}
+		account.Version = current.Version
+	}
+	attributes.apply(&account)
+
+	updated, err := config.UpdateAccount(account)
+	if err != nil {
+		return err
+	}
+	return writeAccounts(stdout, common.output, []accountapi.Account{updated})
+}
+
+func deleteCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("delete")
+	version := flags.Int("version", accountapi.UnknownVersion, "current version, fetched when not given")
+	positional, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+
+	if *version == accountapi.UnknownVersion {
+		current, err := config.FetchAccount(positional[0])
+		if err != nil {
+			return err
+		}
+		*version = current.Version
+	}
+	if err = config.DeleteAccount(positional[0], *version); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Deleted account %v\n", positional[0])
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/accountapitest"
+)
+
+const accountID = "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc"
+
+// runCommand runs accountctl against the fake server and returns exit code and output
+func runCommand(server *accountapitest.Server, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append(args, "-url", server.Config().AccountAPIUrl)
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+
+	code, stdout, stderr := runCommand(server, "create", "-id", accountID, "-organisation-id", "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c",
+		"-country", "GB", "-base-currency", "GBP", "-bic", "NWBKGB22", "-name", "Jane,Doe")
+	if code != exitOK || !strings.Contains(stdout, accountID) {
+		t.Fatalf("Create failed with %v: %v %v", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCommand(server, "get", accountID, "-o", "json")
+	if code != exitOK || !strings.Contains(stdout, `"bic": "NWBKGB22"`) {
+		t.Errorf("Get failed with %v: %v", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(server, "update", accountID, "-bic", "NWBKGB33", "-o", "yaml")
+	if code != exitOK || !strings.Contains(stdout, "version: 1\n") || !strings.Contains(stdout, `  bic: "NWBKGB33"`) {
+		t.Errorf("Update failed with %v: %v", code, stdout)
+	}
+
+	code, _, _ = runCommand(server, "update", accountID, "-version", "0", "-bic", "NWBKGB44")
+	if code != exitConflict {
+		t.Errorf("Update of stale version should exit with %v, got %v", exitConflict, code)
+	}
+
+	code, _, stderr = runCommand(server, "update", accountID, "-organisation-id", "ee2fb143-6dfe-4787-b183-ca8ddd4164d2")
+	if code != exitUsage || !strings.Contains(stderr, "-organisation-id") {
+		t.Errorf("Update of the organisation should exit with %v, got %v: %v", exitUsage, code, stderr)
+	}
+	code, stdout, _ = runCommand(server, "get", accountID, "-o", "json")
+	if code != exitOK || !strings.Contains(stdout, `"organisation_id": "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c"`) || !strings.Contains(stdout, `"version": 1`) {
+		t.Errorf("Rejected update should leave the account unchanged, got %v: %v", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(server, "list", "-filter", "country=GB", "-size", "10")
+	if code != exitOK || !strings.HasPrefix(stdout, "ID") || !strings.Contains(stdout, "NWBKGB33") {
+		t.Errorf("List failed with %v: %v", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(server, "list", "-filter", "country=NL", "-o", "json")
+	if code != exitOK || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("Filtered list should be empty, got %v: %v", code, stdout)
+	}
+
+	code, _, _ = runCommand(server, "delete", accountID)
+	if code != exitOK {
+		t.Errorf("Delete failed with %v", code)
+	}
+
+	code, _, _ = runCommand(server, "get", accountID)
+	if code != exitNotFound {
+		t.Errorf("Get of deleted account should exit with %v, got %v", exitNotFound, code)
+	}
+}
+
+func TestCreateFromFile(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "account.json")
+	ioutil.WriteFile(file, []byte(`{"data":{"id":"`+accountID+`","organisation_id":"eb0bd6f5-c3f5-44b2-b677-acd23cdde73c","attributes":{"country":"NL"}}}`), 0644)
+
+	code, stdout, stderr := runCommand(server, "create", "-file", file, "-bic", "NLABNA01", "-o", "json")
+	if code != exitOK || !strings.Contains(stdout, `"country": "NL"`) || !strings.Contains(stdout, `"bic": "NLABNA01"`) {
+		t.Errorf("Create from file failed with %v: %v %v", code, stdout, stderr)
+	}
+
+	code, _, _ = runCommand(server, "create", "-file", file)
+	if code != exitConflict {
+		t.Errorf("Duplicate create should exit with %v, got %v", exitConflict, code)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+
+	if code, _, _ := runCommand(server, "create", "-id", "invalid"); code != exitInvalid {
+		t.Errorf("Invalid account should exit with %v, got %v", exitInvalid, code)
+	}
+
+	server.FailNext(1, 503, "maintenance")
+	if code, _, _ := runCommand(server, "list"); code != exitServerError {
+		t.Errorf("Server error should exit with %v, got %v", exitServerError, code)
+	}
+
+	if code, _, _ := runCommand(server, "get"); code != exitUsage {
+		t.Errorf("Missing ID should exit with %v, got %v", exitUsage, code)
+	}
+
+	if code := run([]string{"transfer"}, ioutil.Discard, ioutil.Discard); code != exitUsage {
+		t.Errorf("Unknown command should exit with %v, got %v", exitUsage, code)
+	}
+
+	code := run([]string{"list", "-url", "http://127.0.0.1:1/v1/organisation/accounts/"}, ioutil.Discard, ioutil.Discard)
+	if code != exitUnavailable {
+		t.Errorf("Unreachable API should exit with %v, got %v", exitUnavailable, code)
+	}
+}
+
+func TestConfigurationProfile(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "accountctl.yaml")
+	ioutil.WriteFile(file, []byte("profiles:\n  test:\n    url: "+server.Config().AccountAPIUrl+"\n"), 0644)
+
+	var stdout bytes.Buffer
+	if code := run([]string{"list", "-config", file, "-profile", "test", "-o", "json"}, &stdout, ioutil.Discard); code != exitOK {
+		t.Errorf("List with configuration profile failed with %v", code)
+	}
+
+	if code := run([]string{"list", "-config", file, "-profile", "prod"}, ioutil.Discard, ioutil.Discard); code != exitError {
+		t.Errorf("Undefined profile should exit with %v, got %v", exitError, code)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	server := accountapitest.NewServer()
+	defer server.Close()
+
+	runCommand(server, "create", "-id", accountID, "-organisation-id", "eb0bd6f5-c3f5-44b2-b677-acd23cdde73c", "-country", "GB", "-bic", "NWBKGB22")
+
+	// Listed accounts are the desired state, until the file is changed
+	file := filepath.Join(t.TempDir(), "accounts.yaml")
+	_, listed, _ := runCommand(server, "list", "-o", "yaml")
+	ioutil.WriteFile(file, []byte(listed), 0644)
+	code, stdout, stderr := runCommand(server, "plan", "-file", file)
+	if code != exitOK || !strings.HasPrefix(stdout, "No changes, 1 accounts") {
+		t.Fatalf("Plan of listed accounts failed with %v: %v %v", code, stdout, stderr)
+	}
+
+	ioutil.WriteFile(file, []byte(strings.Replace(listed, `"NWBKGB22"`, `"NWBKGB42"`, 1)), 0644)
+	code, stdout, _ = runCommand(server, "apply", "-file", file, "-dry-run")
+	if code != exitOK || !strings.Contains(stdout, `~ attributes.bic: "NWBKGB22" -> "NWBKGB42"`) || strings.Contains(stdout, "Applied") {
+		t.Errorf("Dry run failed with %v: %v", code, stdout)
+	}
+	if server.Accounts()[0].Attributes.Bic != "NWBKGB22" {
+		t.Errorf("Dry run should not change accounts")
+	}
+
+	code, stdout, _ = runCommand(server, "apply", "-file", file)
+	if code != exitOK || !strings.Contains(stdout, "Applied 1 of 1 actions.") || server.Accounts()[0].Attributes.Bic != "NWBKGB42" {
+		t.Errorf("Apply failed with %v: %v", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(server, "plan", "-file", file, "-o", "json")
+	if code != exitOK || !strings.Contains(stdout, `"actions": null`) {
+		t.Errorf("Applied changes should not be planned again, got %v: %v", code, stdout)
+	}
+
+	if code, _, _ := runCommand(server, "plan"); code != exitUsage {
+		t.Errorf("Plan without file should exit with %v, got %v", exitUsage, code)
+	}
+
+	t.Run("Test deleting all accounts needs confirmation", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "accounts.yaml")
+		ioutil.WriteFile(empty, []byte("accounts: []\n"), 0644)
+		if code, _, _ := runCommand(server, "apply", "-file", empty); code != exitError {
+			t.Errorf("File without accounts should exit with %v, got %v", exitError, code)
+		}
+		if code, _, _ := runCommand(server, "apply", "-file", empty, "-allow-empty"); code != exitUsage || len(server.Accounts()) != 1 {
+			t.Errorf("Unfiltered deletes should exit with %v, got %v", exitUsage, code)
+		}
+		if code, _, _ := runCommand(server, "apply", "-file", empty, "-allow-empty", "-filter", "country=NL"); code != exitOK || len(server.Accounts()) != 1 {
+			t.Errorf("Filtered apply should keep accounts that are not managed, got %v", code)
+		}
+		if code, _, _ := runCommand(server, "apply", "-file", empty, "-allow-empty", "-confirm"); code != exitOK || len(server.Accounts()) != 0 {
+			t.Errorf("Confirmed apply should delete all accounts, got %v", code)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// writeAccounts prints accounts in given format
+func writeAccounts(w io.Writer, format string, accounts []accountapi.Account) error {
+	switch format {
+	case formatTable:
+		return writeTable(w, accounts)
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if len(accounts) == 1 {
+			return encoder.Encode(accounts[0])
+		}
+		return encoder.Encode(accounts)
+	case formatYAML:
+		if len(accounts) == 1 {
+			return writeYAML(w, accounts[0])
+		}
+		return writeYAML(w, accounts)
+	}
+	return fmt.Errorf("Unknown output format %q, use one of table, json or yaml", format)
+}
+
+func writeTable(w io.Writer, accounts []accountapi.Account) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tORGANISATION\tCOUNTRY\tCURRENCY\tBIC\tIBAN\tSTATUS\tVERSION")
+	for _, account := range accounts {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			account.ID,
+			account.OrganisationID,
+			account.Attributes.Country,
+			account.Attributes.BaseCurrency,
+			account.Attributes.Bic,
+			account.Attributes.Iban,
+			account.Attributes.Status,
+			account.Version)
+	}
+	return table.Flush()
+}
+
+// writeYAML prints value as YAML, with keys and their order following the JSON field tags.
+// The value is encoded to JSON first, which YAML reads with keys in order; string values are
+// double quoted, so that e.g. an account number of digits is not read back as a number.
+func writeYAML(w io.Writer, value interface{}) error {
+	bJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(bJSON, &document); err != nil {
+		return err
+	}
+	setBlockStyle(&document)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// setBlockStyle replaces the JSON flow style of node and its children by block style
+func setBlockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for i, child := range node.Content {
+		setBlockStyle(child)
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			// Keys stay plain, they are quoted only when they would read as another type
+			child.Style = 0
+		}
+	}
+}
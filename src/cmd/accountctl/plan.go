@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dexpetkovic/zero-one-go/src/accountapi"
+	"github.com/dexpetkovic/zero-one-go/src/accountapi/reconcile"
+)
+
+// planFlags are accepted by plan and apply
+type planFlags struct {
+	file       *string
+	filter     filterFlag
+	ignore     *string
+	allowEmpty *bool
+}
+
+func registerPlanFlags(flags *flag.FlagSet) *planFlags {
+	plan := &planFlags{filter: filterFlag{}}
+	plan.file = flags.String("file", "", "YAML or JSON file with the desired accounts")
+	flags.Var(plan.filter, "filter", "manage only accounts matching key=value, can be repeated")
+	plan.ignore = flags.String("ignore", "", "comma separated fields the API manages, e.g. attributes.status")
+	plan.allowEmpty = flags.Bool("allow-empty", false, "accept a file without accounts, planning to delete all managed accounts")
+	return plan
+}
+
+// makePlan loads the desired accounts and plans changes against the API
+func (flags *planFlags) makePlan(config accountapi.Configuration) (reconcile.Plan, error) {
+	if *flags.file == "" {
+		return reconcile.Plan{}, fmt.Errorf("%w: -file is required", errUsage)
+	}
+	desired, err := reconcile.LoadFile(*flags.file)
+	if errors.Is(err, reconcile.ErrNoAccounts) && *flags.allowEmpty {
+		desired, err = nil, nil
+	}
+	if err != nil {
+		return reconcile.Plan{}, err
+	}
+	options := reconcile.Options{Filter: accountapi.Filter(flags.filter)}
+	if *flags.ignore != "" {
+		options.Ignore = strings.Split(*flags.ignore, ",")
+	}
+	return reconcile.MakePlan(context.Background(), config, desired, options)
+}
+
+// writePlan prints the plan as text for table output, else in given format
+func writePlan(w io.Writer, format string, plan reconcile.Plan) error {
+	switch format {
+	case formatTable:
+		_, err := io.WriteString(w, plan.String())
+		return err
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	case formatYAML:
+		return writeYAML(w, plan)
+	}
+	return fmt.Errorf("Unknown output format %q, use one of table, json or yaml", format)
+}
+
+func planCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("plan")
+	planning := registerPlanFlags(flags)
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+
+	plan, err := planning.makePlan(config)
+	if err != nil {
+		return err
+	}
+	return writePlan(stdout, common.output, plan)
+}
+
+func applyCommand(args []string, stdout io.Writer) error {
+	flags, common := newFlagSet("apply")
+	planning := registerPlanFlags(flags)
+	dryRun := flags.Bool("dry-run", false, "print the plan without applying it")
+	confirm := flags.Bool("confirm", false, "apply deletes although no -filter limits the managed accounts")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	config, err := common.config()
+	if err != nil {
+		return err
+	}
+
+	plan, err := planning.makePlan(config)
+	if err != nil {
+		return err
+	}
+	if err = writePlan(stdout, common.output, plan); err != nil || *dryRun || plan.Empty() {
+		return err
+	}
+	// Without a filter, every account of the API missing from the file is deleted
+	if deletes := plan.Count(reconcile.Delete); deletes > 0 && len(planning.filter) == 0 && !*confirm {
+		return fmt.Errorf("%w: plan deletes %v accounts and no -filter limits the managed accounts, use -filter or -confirm", errUsage, deletes)
+	}
+
+	applied, err := reconcile.Apply(context.Background(), config, plan)
+	if common.output == formatTable {
+		fmt.Fprintf(stdout, "Applied %v of %v actions.\n", len(applied), len(plan.Actions))
+	}
+	return err
+}
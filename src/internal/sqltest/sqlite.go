@@ -0,0 +1,5 @@
+//go:build sqlite
+
+package sqltest
+
+import _ "github.com/mattn/go-sqlite3"
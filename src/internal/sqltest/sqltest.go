@@ -0,0 +1,27 @@
+// Package sqltest opens SQLite databases for the tests of packages that store accounts in SQL.
+// The driver is not a dependency of the library, so it is only registered when the tests are
+// built with the sqlite tag, e.g. go test -tags sqlite ./src/accountapi/mirror/
+package sqltest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// Open opens an empty SQLite database that is closed when the test ends. The test is skipped
+// when no SQLite driver is registered.
+func Open(t testing.TB) *sql.DB {
+	for _, name := range sql.Drivers() {
+		if name == "sqlite" || name == "sqlite3" {
+			db, err := sql.Open(name, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Could not open database: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			return db
+		}
+	}
+	t.Skip("No SQLite driver registered, run the tests with -tags sqlite")
+	return nil
+}